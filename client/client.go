@@ -14,9 +14,12 @@ import (
 	"github.com/soundtrackyourbrand/utils/email"
 	"github.com/soundtrackyourbrand/utils/json"
 	"github.com/soundtrackyourbrand/utils/key"
+	"github.com/soundtrackyourbrand/utils/retry"
 	"github.com/soundtrackyourbrand/utils/web/jsoncontext"
 )
 
+//go:generate go run ../key/keygen/main.go -kind=Account,Location,SoundZone -output=keys_keygen.go
+
 const (
 	MinAPIVersion = 1
 	MaxAPIVersion = 7
@@ -78,23 +81,23 @@ type DeviceHierarchy struct {
 type RemoteDevice struct {
 	DefaultMeta
 
-	DeviceId        string  `json:"device_id"`
-	PairingCode     string  `json:"pairing_code"`
-	VendorId        string  `json:"vendor_id"`
-	DeviceType      string  `json:"device_type"`
-	SoundZone       key.Key `json:"sound_zone"`
-	Label           string  `json:"label"`
-	Name            string  `json:"name"`
-	SoftwareVersion string  `json:"software_version"`
-	Platform        string  `json:"platform"`
-	PairingState    string  `json:"pairing_state"`
-	SoftPair        bool    `json:"soft_pair"`
+	DeviceId        string       `json:"device_id"`
+	PairingCode     string       `json:"pairing_code"`
+	VendorId        string       `json:"vendor_id"`
+	DeviceType      string       `json:"device_type"`
+	SoundZone       SoundZoneKey `json:"sound_zone"`
+	Label           string       `json:"label"`
+	Name            string       `json:"name"`
+	SoftwareVersion string       `json:"software_version"`
+	Platform        string       `json:"platform"`
+	PairingState    string       `json:"pairing_state"`
+	SoftPair        bool         `json:"soft_pair"`
 }
 
 type RemoteLocation struct {
 	DefaultMeta
 
-	Account key.Key `json:"account"`
+	Account AccountKey `json:"account"`
 
 	Name         string  `json:"name"`
 	PostalCode   string  `json:"postal_code"`
@@ -215,20 +218,20 @@ type RemoteAccount struct {
 
 type RemoteSoundZone struct {
 	DefaultMeta
-	Account                   key.Key    `json:"account,omitempty"`
-	Location                  key.Key    `json:"location,omitempty"`
-	Comment                   string     `json:"comment,omitempty"`
-	Email                     string     `json:"email,omitempty"`
-	Name                      string     `json:"name,omitempty"`
-	Serial                    string     `json:"serial,omitempty"`
-	SpotifyUsername           string     `json:"spotify_username,omitempty"`
-	SpotifyPassword           string     `json:"spotify_password,omitempty"`
-	PaidUntil                 utils.Time `json:"iso8601_paid_until"`
-	BilledUntil               utils.Time `json:"iso8601_billed_until,omitempty"`
-	Schedule                  key.Key    `json:"schedule,omitempty"`
-	Deactivated               bool       `json:"deactivated"`
-	SpotifyAccountDeactivated bool       `json:"spotify_account_deactivated"`
-	DeviceId                  string     `json:"device_id,omitempty"`
+	Account                   AccountKey  `json:"account,omitempty"`
+	Location                  LocationKey `json:"location,omitempty"`
+	Comment                   string      `json:"comment,omitempty"`
+	Email                     string      `json:"email,omitempty"`
+	Name                      string      `json:"name,omitempty"`
+	Serial                    string      `json:"serial,omitempty"`
+	SpotifyUsername           string      `json:"spotify_username,omitempty"`
+	SpotifyPassword           string      `json:"spotify_password,omitempty"`
+	PaidUntil                 utils.Time  `json:"iso8601_paid_until"`
+	BilledUntil               utils.Time  `json:"iso8601_billed_until,omitempty"`
+	Schedule                  key.Key     `json:"schedule,omitempty"`
+	Deactivated               bool        `json:"deactivated"`
+	SpotifyAccountDeactivated bool        `json:"spotify_account_deactivated"`
+	DeviceId                  string      `json:"device_id,omitempty"`
 }
 
 type RemoteSoundZones []RemoteSoundZone
@@ -259,16 +262,16 @@ type RemoteSpotifyAccounts []RemoteSpotifyAccount
 
 type RemoteSpotifyAccount struct {
 	DefaultMeta
-	SoundZone          key.Key    `json:"sound_zone" datastore:"-"`
-	PaidUntil          utils.Time `json:"iso8601_paid_until"`
-	ProductCode        string     `json:"current_product_code"`
-	NextProductCode    []string   `json:"next_product_code"`
-	IsRecurring        bool       `json:"is_recurring"`
-	LastAutoPayFailure bool       `json:"last_auto_pay_failure"`
-	Deactivated        bool       `json:"deactivated"`
-	Username           string     `json:"username"`
-	Account            key.Key    `json:"account" datastore:"-"`
-	ISOCountry         string     `json:"iso_country"`
+	SoundZone          SoundZoneKey `json:"sound_zone" datastore:"-"`
+	PaidUntil          utils.Time   `json:"iso8601_paid_until"`
+	ProductCode        string       `json:"current_product_code"`
+	NextProductCode    []string     `json:"next_product_code"`
+	IsRecurring        bool         `json:"is_recurring"`
+	LastAutoPayFailure bool         `json:"last_auto_pay_failure"`
+	Deactivated        bool         `json:"deactivated"`
+	Username           string       `json:"username"`
+	Account            AccountKey   `json:"account" datastore:"-"`
+	ISOCountry         string       `json:"iso_country"`
 }
 
 type RemotePending struct {
@@ -285,9 +288,11 @@ func errorFor(request *http.Request, response *http.Response) (err error) {
 	return
 }
 
+// RequestRetry is how DoRequest retries requests that fail, or get a server error.
+var RequestRetry = retry.DefaultPolicy
+
 func DoRequest(c ServiceConnector, method, service, path string, token AccessToken, body interface{}) (request *http.Request, response *http.Response, err error) {
-	waitTime := time.Millisecond * 100
-	for waitTime < time.Second*10 {
+	for attempt := 0; ; attempt++ {
 		buf := new(bytes.Buffer)
 		if body != nil {
 			if err = json.NewEncoder(buf).Encode(body); err != nil {
@@ -319,8 +324,11 @@ func DoRequest(c ServiceConnector, method, service, path string, token AccessTok
 		if err == nil && response.StatusCode < 500 {
 			break
 		}
-		time.Sleep(waitTime)
-		waitTime = waitTime * 2
+		delay, again := RequestRetry.Next(attempt)
+		if !again {
+			break
+		}
+		time.Sleep(delay)
 	}
 	if err != nil {
 		return
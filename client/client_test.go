@@ -0,0 +1,41 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soundtrackyourbrand/utils/retry"
+)
+
+type testConnector struct{}
+
+func (testConnector) GetAuthService() string    { return "" }
+func (testConnector) GetRadioService() string   { return "" }
+func (testConnector) GetPaymentService() string { return "" }
+func (testConnector) Client() *http.Client      { return http.DefaultClient }
+
+func TestDoRequest(t *testing.T) {
+	requests := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer func(policy retry.Policy) {
+		RequestRetry = policy
+	}(RequestRetry)
+	// the delay after the second, and last, attempt would be 5s
+	RequestRetry = retry.Policy{MaxAttempts: 2, Backoff: retry.Backoff{Initial: time.Millisecond, Multiplier: 5000}}
+
+	start := time.Now()
+	_, response, err := DoRequest(testConnector{}, "GET", server.URL, "x", nil, nil)
+	if err != nil || response.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("Wanted 2 requests ending with a 503, got %v requests, %v, %v", requests, response, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Wanted the last failure to return without sleeping, it took %v", elapsed)
+	}
+}
@@ -450,6 +450,30 @@ type ElasticDoc struct {
 	Source map[string]*json.RawMessage `json:"_source"`
 }
 
+/*
+Get returns the value the JSON Pointer points to in the source of the document, and whether there was one, without decoding
+the rest of the source.
+*/
+func (self *ElasticDoc) Get(pointer string) (result json.RawMessage, found bool, err error) {
+	if !strings.HasPrefix(pointer, "/") {
+		err = fmt.Errorf("%#v is not a JSON Pointer into a source", pointer)
+		return
+	}
+	first, rest := pointer, ""
+	if i := strings.Index(pointer[1:], "/"); i != -1 {
+		first, rest = pointer[:i+1], pointer[i+1:]
+	}
+	tokens, err := json.ParsePointer(first)
+	if err != nil {
+		return
+	}
+	value := self.Source[tokens[0]]
+	if value == nil {
+		return
+	}
+	return value.Get(rest)
+}
+
 type Hits struct {
 	Total    int          `json:"total"`
 	MaxScore float64      `json:"max_score"`
@@ -556,6 +580,20 @@ type RangeDef struct {
 	Boost string `json:"boost,omitempty"`
 }
 
+/*
+KeyRangeFilter returns a filter matching documents whose field, containing the key.Key.SortKey of a key, is in r.
+*/
+func KeyRangeFilter(field string, r key.Range) Filter {
+	return Filter{
+		Range: map[string]RangeDef{
+			field: RangeDef{
+				Gte: r.Start.SortKey(),
+				Lt:  r.End.SortKey(),
+			},
+		},
+	}
+}
+
 func SearchAndCopy(c ElasticSearchContext, query *SearchRequest, index string, result interface{}) (err error) {
 	name := reflect.ValueOf(result).Elem().FieldByName("Items").Type().Elem().Name()
 	response, err := Search(c, query, index, name)
@@ -414,7 +414,7 @@ push this particular instance into BigQuery.
 func (self *BigQuery) InsertTableData(i interface{}) (err error) {
 	j := map[string]gbigquery.JsonValue{}
 
-	b, err := json.Marshal(i, "bigquery")
+	b, err := json.Marshal(i, json.Options{Context: "bigquery"})
 	if err != nil {
 		return
 	}
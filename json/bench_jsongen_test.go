@@ -0,0 +1,98 @@
+// Code generated by "jsongen -type=genCodeResponse,genCodeNode"; DO NOT EDIT.
+
+package json
+
+var jsonKeysGenCodeResponse = []string{
+	"tree",
+	"username",
+}
+
+func (self genCodeResponse) MarshalJSON(args ...interface{}) ([]byte, error) {
+	return MarshalGenerated(&self, args...)
+}
+
+func (self *genCodeResponse) EncodeJSON(enc *GeneratedEncoder) {
+	enc.Begin()
+	enc.Key("tree")
+	enc.Value(&self.Tree, false, "")
+	enc.Key("username")
+	enc.String(self.Username, false)
+	enc.End()
+}
+
+func (self *genCodeResponse) UnmarshalJSON(data []byte, args ...interface{}) error {
+	return UnmarshalGenerated(data, self, args...)
+}
+
+func (self *genCodeResponse) DecodeJSON(dec *GeneratedDecoder) {
+	for dec.Next() {
+		switch dec.Match(jsonKeysGenCodeResponse) {
+		case 0:
+			dec.Value(&self.Tree, false, "")
+		case 1:
+			dec.Value(&self.Username, false, "")
+		default:
+			dec.Skip()
+		}
+	}
+}
+
+var jsonKeysGenCodeNode = []string{
+	"name",
+	"kids",
+	"cl_weight",
+	"touches",
+	"min_t",
+	"max_t",
+	"mean_t",
+}
+
+func (self genCodeNode) MarshalJSON(args ...interface{}) ([]byte, error) {
+	return MarshalGenerated(&self, args...)
+}
+
+func (self *genCodeNode) EncodeJSON(enc *GeneratedEncoder) {
+	enc.Begin()
+	enc.Key("name")
+	enc.String(self.Name, false)
+	enc.Key("kids")
+	enc.Value(&self.Kids, false, "")
+	enc.Key("cl_weight")
+	enc.Float(self.CLWeight, 64, false)
+	enc.Key("touches")
+	enc.Int(int64(self.Touches), false)
+	enc.Key("min_t")
+	enc.Int(int64(self.MinT), false)
+	enc.Key("max_t")
+	enc.Int(int64(self.MaxT), false)
+	enc.Key("mean_t")
+	enc.Int(int64(self.MeanT), false)
+	enc.End()
+}
+
+func (self *genCodeNode) UnmarshalJSON(data []byte, args ...interface{}) error {
+	return UnmarshalGenerated(data, self, args...)
+}
+
+func (self *genCodeNode) DecodeJSON(dec *GeneratedDecoder) {
+	for dec.Next() {
+		switch dec.Match(jsonKeysGenCodeNode) {
+		case 0:
+			dec.Value(&self.Name, false, "")
+		case 1:
+			dec.Value(&self.Kids, false, "")
+		case 2:
+			dec.Value(&self.CLWeight, false, "")
+		case 3:
+			dec.Value(&self.Touches, false, "")
+		case 4:
+			dec.Value(&self.MinT, false, "")
+		case 5:
+			dec.Value(&self.MaxT, false, "")
+		case 6:
+			dec.Value(&self.MeanT, false, "")
+		default:
+			dec.Skip()
+		}
+	}
+}
@@ -33,8 +33,27 @@ type codeNode struct {
 	MeanT    int64       `json:"mean_t"`
 }
 
+//go:generate go run jsongen/main.go -type=genCodeResponse,genCodeNode -output=bench_jsongen_test.go
+
+// genCodeResponse and genCodeNode are copies of codeResponse and codeNode with methods generated by jsongen.
+type genCodeResponse struct {
+	Tree     *genCodeNode `json:"tree"`
+	Username string       `json:"username"`
+}
+
+type genCodeNode struct {
+	Name     string         `json:"name"`
+	Kids     []*genCodeNode `json:"kids"`
+	CLWeight float64        `json:"cl_weight"`
+	Touches  int            `json:"touches"`
+	MinT     int64          `json:"min_t"`
+	MaxT     int64          `json:"max_t"`
+	MeanT    int64          `json:"mean_t"`
+}
+
 var codeJSON []byte
 var codeStruct codeResponse
+var genCodeStruct genCodeResponse
 
 func codeInit() {
 	f, err := os.Open("testdata/code.json.gz")
@@ -73,6 +92,18 @@ func codeInit() {
 		}
 		panic("re-marshal code.json: different result")
 	}
+
+	if err := Unmarshal(codeJSON, &genCodeStruct); err != nil {
+		panic("generated unmarshal code.json: " + err.Error())
+	}
+
+	if data, err = Marshal(&genCodeStruct); err != nil {
+		panic("generated marshal code.json: " + err.Error())
+	}
+
+	if !bytes.Equal(data, codeJSON) {
+		panic("generated re-marshal code.json: different result")
+	}
 }
 
 func BenchmarkCodeEncoder(b *testing.B) {
@@ -104,6 +135,20 @@ func BenchmarkCodeMarshal(b *testing.B) {
 	b.SetBytes(int64(len(codeJSON)))
 }
 
+func BenchmarkCodeMarshalGenerated(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(&genCodeStruct); err != nil {
+			b.Fatal("Marshal:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
 func BenchmarkCodeDecoder(b *testing.B) {
 	if codeJSON == nil {
 		b.StopTimer()
@@ -141,6 +186,21 @@ func BenchmarkCodeUnmarshal(b *testing.B) {
 	b.SetBytes(int64(len(codeJSON)))
 }
 
+func BenchmarkCodeUnmarshalGenerated(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	for i := 0; i < b.N; i++ {
+		var r genCodeResponse
+		if err := Unmarshal(codeJSON, &r); err != nil {
+			b.Fatal("Unmmarshal:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
 func BenchmarkCodeUnmarshalReuse(b *testing.B) {
 	if codeJSON == nil {
 		b.StopTimer()
@@ -0,0 +1,182 @@
+package json
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type canonicalArg string
+
+/*
+Canonical makes Marshal, AppendMarshal and Encoder.Encode produce canonical JSON, as Canonicalize does, when passed among their args.
+
+The other args are still passed on to the Marshalers being encoded.
+*/
+const Canonical = canonicalArg("canonical")
+
+/*
+Canonicalize appends to dst the JSON-encoded src in the canonical form of RFC 8785 (JSON Canonicalization Scheme), making equal
+values encode to equal bytes, for signatures or content hashes:
+
+Object members are sorted by the UTF-16 code units of their keys, at every level.
+
+Numbers are formatted like ECMAScript does, as the shortest decimal that parses back to the same IEEE 754 double,
+so integers beyond 2^53 lose precision.
+
+Strings only escape quotation marks, reverse solidi and control characters, so <, > and & are not HTML escaped.
+
+There is no insignificant whitespace.
+*/
+func Canonicalize(dst *bytes.Buffer, src []byte) error {
+	d := &decodeState{useNumber: true}
+	if err := checkValid(src, &d.scan); err != nil {
+		return err
+	}
+	d.init(src)
+	var v interface{}
+	if err := d.unmarshal(&v); err != nil {
+		return err
+	}
+	origLen := dst.Len()
+	if err := canonicalValue(dst, v); err != nil {
+		dst.Truncate(origLen)
+		return err
+	}
+	return nil
+}
+
+func canonicalValue(dst *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		dst.WriteString("null")
+	case bool:
+		if v {
+			dst.WriteString("true")
+		} else {
+			dst.WriteString("false")
+		}
+	case Number:
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		var scratch [64]byte
+		b, err := canonicalNumber(scratch[:0], f)
+		if err != nil {
+			return err
+		}
+		dst.Write(b)
+	case string:
+		canonicalString(dst, v)
+	case []interface{}:
+		dst.WriteByte('[')
+		for index, elem := range v {
+			if index > 0 {
+				dst.WriteByte(',')
+			}
+			if err := canonicalValue(dst, elem); err != nil {
+				return err
+			}
+		}
+		dst.WriteByte(']')
+	case map[string]interface{}:
+		keys := make(utf16Keys, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Sort(keys)
+		dst.WriteByte('{')
+		for index, key := range keys {
+			if index > 0 {
+				dst.WriteByte(',')
+			}
+			canonicalString(dst, key)
+			dst.WriteByte(':')
+			if err := canonicalValue(dst, v[key]); err != nil {
+				return err
+			}
+		}
+		dst.WriteByte('}')
+	}
+	return nil
+}
+
+// canonicalNumber appends f to b formatted like the ECMAScript Number.prototype.toString.
+func canonicalNumber(b []byte, f float64) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, &UnsupportedValueError{Str: strconv.FormatFloat(f, 'g', -1, 64)}
+	}
+	if f == 0 {
+		// Negative zero too.
+		return append(b, '0'), nil
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.AppendFloat(b, f, 'f', -1, 64), nil
+	}
+	b = strconv.AppendFloat(b, f, 'e', -1, 64)
+	// Drop the leading zero of two digit exponents, like e-07.
+	if n := len(b); b[n-4] == 'e' && b[n-2] == '0' {
+		b[n-2] = b[n-1]
+		b = b[:n-1]
+	}
+	return b, nil
+}
+
+func canonicalString(dst *bytes.Buffer, s string) {
+	dst.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b >= utf8.RuneSelf || (b >= 0x20 && b != '"' && b != '\\') {
+			i++
+			continue
+		}
+		if start < i {
+			dst.WriteString(s[start:i])
+		}
+		switch b {
+		case '"', '\\':
+			dst.WriteByte('\\')
+			dst.WriteByte(b)
+		case '\b':
+			dst.WriteString(`\b`)
+		case '\f':
+			dst.WriteString(`\f`)
+		case '\n':
+			dst.WriteString(`\n`)
+		case '\r':
+			dst.WriteString(`\r`)
+		case '\t':
+			dst.WriteString(`\t`)
+		default:
+			dst.WriteString(`\u00`)
+			dst.WriteByte(hex[b>>4])
+			dst.WriteByte(hex[b&0xF])
+		}
+		i++
+		start = i
+	}
+	if start < len(s) {
+		dst.WriteString(s[start:])
+	}
+	dst.WriteByte('"')
+}
+
+// utf16Keys sorts object keys by their UTF-16 code units, as RFC 8785 requires.
+type utf16Keys []string
+
+func (self utf16Keys) Len() int      { return len(self) }
+func (self utf16Keys) Swap(i, j int) { self[i], self[j] = self[j], self[i] }
+func (self utf16Keys) Less(i, j int) bool {
+	a, b := utf16.Encode([]rune(self[i])), utf16.Encode([]rune(self[j]))
+	for index := 0; index < len(a) && index < len(b); index++ {
+		if a[index] != b[index] {
+			return a[index] < b[index]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,92 @@
+package json
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want string
+	}{
+		// From RFC 8785, section 3.2.2.
+		{
+			in: `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			want: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		// From RFC 8785, section 3.2.3.
+		{
+			in:   `{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`,
+			want: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			in:   `[ -0, 1e21, 1e20, 1e-7, 0.000001, 5e-324, 100, -1.5e300, "<&>", {"b":{"d":1,"c":2},"a":[]} ]`,
+			want: `[0,1e+21,100000000000000000000,1e-7,0.000001,5e-324,100,-1.5e+300,"<&>",{"a":[],"b":{"c":2,"d":1}}]`,
+		},
+	} {
+		var buf bytes.Buffer
+		if err := Canonicalize(&buf, []byte(c.in)); err != nil {
+			t.Fatalf("%v: %v", c.in, err)
+		}
+		if got := buf.String(); got != c.want {
+			t.Errorf("%v: wanted %v, got %v", c.in, c.want, got)
+		}
+	}
+
+	buf := bytes.NewBufferString("x")
+	if err := Canonicalize(buf, []byte(`{"a":1,}`)); err == nil {
+		t.Errorf("Wanted an error for invalid JSON")
+	}
+	if buf.String() != "x" {
+		t.Errorf("Wanted dst to be left alone on errors, got %v", buf.String())
+	}
+}
+
+type canonicalStruct struct {
+	Z     string  `json:"z"`
+	A     float64 `json:"a"`
+	M     map[string]int
+	Inner struct {
+		Y bool `json:"y"`
+		B int  `json:"b"`
+	} `json:"inner"`
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	v := canonicalStruct{Z: "<z>", A: 1e-7, M: map[string]int{"b": 2, "a": 1}}
+	v.Inner.Y = true
+	want := `{"M":{"a":1,"b":2},"a":1e-7,"inner":{"b":0,"y":true},"z":"<z>"}`
+	b, err := Marshal(v, Canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("Wanted %v, got %v", want, string(b))
+	}
+
+	b, err = AppendMarshal([]byte("prefix "), v, "x", Canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "prefix "+want {
+		t.Errorf("Wanted prefix %v, got %v", want, string(b))
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v, Canonical); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want+"\n" {
+		t.Errorf("Wanted %v, got %v", want, buf.String())
+	}
+
+	if _, err := Marshal(math.Inf(1), Canonical); err == nil {
+		t.Errorf("Wanted an error for infinite numbers")
+	}
+}
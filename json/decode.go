@@ -8,7 +8,6 @@
 package json
 
 import (
-	"bytes"
 	"encoding"
 	"encoding/base64"
 	"errors"
@@ -16,6 +15,7 @@ import (
 	"reflect"
 	"runtime"
 	"strconv"
+	"time"
 	"unicode"
 	"unicode/utf16"
 	"unicode/utf8"
@@ -69,7 +69,7 @@ func Unmarshal(data []byte, v interface{}, args ...interface{}) error {
 	// Avoids filling out half a data structure
 	// before discovering a JSON syntax error.
 	var d decodeState
-	d.args = args
+	d.setArgs(args)
 	err := checkValid(data, &d.scan)
 	if err != nil {
 		return err
@@ -181,6 +181,8 @@ type decodeState struct {
 	tempstr    string // scratch space to avoid some allocations
 	useNumber  bool
 	args       []interface{}
+	opts       Options
+	generated  GeneratedDecoder
 }
 
 // errPhase is used for errors that should not happen unless
@@ -474,6 +476,10 @@ func (d *decodeState) object(v reflect.Value) {
 		}
 		return
 	}
+	if g, ok := u.(GeneratedUnmarshaler); ok {
+		g.DecodeJSON(d.generatedDecoder())
+		return
+	}
 	if u != nil {
 		d.off--
 		err := u.UnmarshalJSON(d.next(), d.args...)
@@ -542,6 +548,7 @@ func (d *decodeState) object(v reflect.Value) {
 		// Figure out field corresponding to key.
 		var subv reflect.Value
 		destring := false // whether the value is wrapped in a string to be decoded first
+		to := ""          // the jsonTo directive of the field
 
 		if v.Kind() == reflect.Map {
 			elemType := v.Type().Elem()
@@ -552,21 +559,11 @@ func (d *decodeState) object(v reflect.Value) {
 			}
 			subv = mapElem
 		} else {
-			var f *field
-			fields := cachedTypeFields(v.Type())
-			for i := range fields {
-				ff := &fields[i]
-				if bytes.Equal(ff.nameBytes, key) {
-					f = ff
-					break
-				}
-				if f == nil && ff.equalFold(ff.nameBytes, key) {
-					f = ff
-				}
-			}
+			f := lookupField(cachedTypeFields(v.Type()), key, d.opts.APIVersion)
 			if f != nil {
 				subv = v
 				destring = f.quoted
+				to = f.to
 				for _, i := range f.index {
 					if subv.Kind() == reflect.Ptr {
 						if subv.IsNil() {
@@ -592,6 +589,8 @@ func (d *decodeState) object(v reflect.Value) {
 			d.value(reflect.ValueOf(&d.tempstr))
 			d.literalStore([]byte(d.tempstr), subv, true)
 			d.tempstr = "" // Zero scratch space for successive values.
+		} else if to != "" {
+			d.jsonToValue(subv, to)
 		} else {
 			d.value(subv)
 		}
@@ -614,6 +613,134 @@ func (d *decodeState) object(v reflect.Value) {
 	}
 }
 
+var (
+	unmarshalerType       = reflect.TypeOf(new(Unmarshaler)).Elem()
+	simpleUnmarshalerType = reflect.TypeOf(new(SimpleUnmarshaler)).Elem()
+	textUnmarshalerType   = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
+)
+
+// acceptsString reports whether values of t decode JSON strings by themselves.
+func acceptsString(t reflect.Type) bool {
+	for {
+		for _, u := range []reflect.Type{unmarshalerType, simpleUnmarshalerType, textUnmarshalerType} {
+			if t.Implements(u) || reflect.PtrTo(t).Implements(u) {
+				return true
+			}
+		}
+		if t.Kind() != reflect.Ptr {
+			break
+		}
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Interface:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+// allocIndirect walks down v allocating pointers as needed,
+// until it gets to a non-pointer.
+func allocIndirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// jsonToValue decodes a JSON value from d.data[d.off:] into v,
+// undoing the coercion made by the jsonTo directive to.
+func (d *decodeState) jsonToValue(v reflect.Value, to string) {
+	var raw RawMessage
+	d.value(reflect.ValueOf(&raw))
+	item := []byte(raw)
+	if item[0] == 'n' {
+		d.storeJSON(item, v)
+		return
+	}
+
+	switch to {
+	case JSONToString:
+		if item[0] == '"' && !acceptsString(v.Type()) {
+			s, ok := unquoteBytes(item)
+			if !ok {
+				d.error(errPhase)
+			}
+			d.storeJSON(s, v)
+			return
+		}
+		d.storeJSON(item, v)
+
+	case JSONToInt, JSONToFloat, JSONToUnixMillis:
+		num := item
+		if item[0] == '"' {
+			num, _ = unquoteBytes(item)
+		}
+		if to == JSONToFloat {
+			if _, ok := parseJSONToFloat(num); !ok {
+				d.saveError(&UnmarshalTypeError{"string " + string(item), v.Type()})
+				return
+			}
+		} else if i, ok := parseJSONToInt(num); !ok {
+			d.saveError(&UnmarshalTypeError{"string " + string(item), v.Type()})
+			return
+		} else {
+			num = []byte(strconv.FormatInt(i, 10))
+		}
+		pv := allocIndirect(v)
+		switch {
+		case to == JSONToUnixMillis:
+			if pv.Type() != timeType {
+				d.saveError(&UnmarshalTypeError{"number " + string(num), v.Type()})
+				return
+			}
+			ms, _ := strconv.ParseInt(string(num), 10, 64)
+			pv.Set(reflect.ValueOf(time.Unix(0, ms*int64(time.Millisecond)).UTC()))
+		case pv.Kind() == reflect.String:
+			pv.SetString(string(num))
+		default:
+			d.storeJSON(num, v)
+		}
+
+	case JSONToBase64:
+		s, ok := unquoteBytes(item)
+		if !ok {
+			d.saveError(&UnmarshalTypeError{string(item), v.Type()})
+			return
+		}
+		b, err := base64.StdEncoding.DecodeString(string(s))
+		if err != nil {
+			d.saveError(err)
+			return
+		}
+		pv := allocIndirect(v)
+		switch {
+		case pv.Kind() == reflect.String:
+			pv.SetString(string(b))
+		case pv.Kind() == reflect.Slice && pv.Type().Elem().Kind() == reflect.Uint8:
+			pv.SetBytes(b)
+		default:
+			d.saveError(&UnmarshalTypeError{"string", v.Type()})
+		}
+
+	default:
+		d.saveError(fmt.Errorf("json: unknown jsonTo directive %q for %v", to, v.Type()))
+	}
+}
+
+// storeJSON unmarshals the complete JSON value item into v,
+// using the same args as d.
+func (d *decodeState) storeJSON(item []byte, v reflect.Value) {
+	if err := Unmarshal(item, v.Addr().Interface(), d.args...); err != nil {
+		d.saveError(err)
+	}
+}
+
 // literal consumes a literal from d.data[d.off-1:], decoding into the value v.
 // The first byte of the literal has been read already
 // (that's how the caller knows it's a literal).
@@ -658,6 +785,19 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 	}
 	wantptr := item[0] == 'n' // null
 	u, ut, su, pv := d.indirect(v, wantptr)
+	if t, ok := ut.(*time.Time); ok && item[0] == '"' && d.opts.TimeFormat != "" {
+		// Decode time.Time values with the TimeFormat of the Options.
+		s, ok := unquote(item)
+		if !ok {
+			d.error(errPhase)
+		}
+		parsed, err := time.Parse(d.opts.TimeFormat, s)
+		if err != nil {
+			d.error(err)
+		}
+		*t = parsed
+		return
+	}
 	if su != nil {
 		err := su.UnmarshalJSON(item)
 		if err != nil {
@@ -1445,3 +1445,47 @@ func TestInvalidUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONToRoundTrip(t *testing.T) {
+	tp := time.Unix(1300000000, 0).UTC()
+	s := JSONToStruct{
+		S:  12,
+		SS: "hello",
+		I:  "42",
+		F:  "1.5",
+		B:  "hello",
+		BB: []byte("world"),
+		T:  time.Unix(1400000000, 123000000).UTC(),
+		TP: &tp,
+	}
+	b, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s2 JSONToStruct
+	if err := Unmarshal(b, &s2); err != nil {
+		t.Fatalf("Unable to unmarshal %s: %v", b, err)
+	}
+	if !reflect.DeepEqual(s, s2) {
+		t.Fatalf("Wanted %+v, got %+v", s, s2)
+	}
+
+	// Uncoerced input is accepted as well.
+	var s3 JSONToStruct
+	if err := Unmarshal([]byte(`{"S":13,"I":"7.0","F":2}`), &s3); err != nil {
+		t.Fatal(err)
+	}
+	if s3.S != 13 || s3.I != "7" || s3.F != "2" {
+		t.Fatalf("Wrong decoding of uncoerced input: %+v", s3)
+	}
+	for _, in := range []string{`{"I":"7.9"}`, `{"I":1e30}`, `{"T":1.5}`, `{"F":"NaN"}`} {
+		if err := Unmarshal([]byte(in), &s3); err == nil {
+			t.Errorf("Decoding %v should fail", in)
+		} else if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Decoding %v should fail with an *UnmarshalTypeError, got %#v", in, err)
+		}
+	}
+	if err := Unmarshal([]byte(`{"B":"not base64"}`), &s3); err == nil {
+		t.Fatalf("Decoding invalid base64 should fail")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 	"unicode/utf8"
 )
@@ -84,6 +85,20 @@ import (
 //
 //    Int64String int64 `json:",string"`
 //
+// The separate "jsonTo" tag coerces the encoding of any field, regardless
+// of its Go type, and is undone by Unmarshal:
+//
+//   // Encoded as a JSON string, quoting it if it isn't one already.
+//   Field int `jsonTo:"string"`
+//   // Encoded as a JSON number, parsing it if it is a string. Values that
+//   // aren't finite numbers, or for int integers in the range of int64, fail.
+//   Field string `jsonTo:"int"`
+//   Field string `jsonTo:"float"`
+//   // A string or []byte encoded as a base64 JSON string.
+//   Field string `jsonTo:"base64"`
+//   // A time.Time encoded as milliseconds since the Unix epoch.
+//   Field time.Time `jsonTo:"unix-millis"`
+//
 // The key name will be used if it's a non-empty string consisting of
 // only Unicode letters, digits, dollar signs, percent signs, hyphens,
 // underscores and slashes.
@@ -128,24 +143,47 @@ import (
 // handle them.  Passing cyclic structures to Marshal will result in
 // an infinite recursion.
 //
+// The args are passed on to every Marshaler. Options among them
+// configure the encoding, see Options.
+//
+// If Canonical is among the args, the output is canonicalized as by
+// Canonicalize, so that equal values always produce the same bytes.
+//
 func Marshal(v interface{}, args ...interface{}) ([]byte, error) {
-	e := &encodeState{}
-	e.args = args
+	return AppendMarshal(nil, v, args...)
+}
+
+// AppendMarshal is like Marshal but appends the JSON encoding of v to dst,
+// so that callers encoding many values can reuse their buffer.
+func AppendMarshal(dst []byte, v interface{}, args ...interface{}) ([]byte, error) {
+	e := newEncodeState()
+	defer putEncodeState(e)
+	e.setArgs(args)
 	err := e.marshal(v)
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
-	return e.Bytes(), nil
+	if e.opts.Canonical {
+		var buf bytes.Buffer
+		if err = Canonicalize(&buf, e.Bytes()); err != nil {
+			return dst, err
+		}
+		return append(dst, buf.Bytes()...), nil
+	}
+	return append(dst, e.Bytes()...), nil
 }
 
 // MarshalIndent is like Marshal but applies Indent to format the output.
 func MarshalIndent(v interface{}, prefix, indent string, args ...interface{}) ([]byte, error) {
-	b, err := Marshal(v, args...)
+	e := newEncodeState()
+	defer putEncodeState(e)
+	e.setArgs(args)
+	err := e.marshal(v)
 	if err != nil {
 		return nil, err
 	}
 	var buf bytes.Buffer
-	err = Indent(&buf, b, prefix, indent)
+	err = Indent(&buf, e.Bytes(), prefix, indent)
 	if err != nil {
 		return nil, err
 	}
@@ -246,25 +284,28 @@ type encodeState struct {
 	bytes.Buffer // accumulated output
 	scratch      [64]byte
 	args         []interface{}
+	opts         Options
+	generated    GeneratedEncoder
 }
 
-/*
-uncomment when go_appengine runs 1.3...
 var encodeStatePool sync.Pool
-*/
 
 func newEncodeState() *encodeState {
-	/*
-		uncomment when go_appengine runs 1.3...
-		if v := encodeStatePool.Get(); v != nil {
-			e := v.(*encodeState)
-			e.Reset()
-			return e
-		}
-	*/
+	if v := encodeStatePool.Get(); v != nil {
+		e := v.(*encodeState)
+		e.Reset()
+		return e
+	}
 	return new(encodeState)
 }
 
+// putEncodeState returns e to the pool. The buffer of e must not be used afterwards.
+func putEncodeState(e *encodeState) {
+	e.args = nil
+	e.opts = Options{}
+	encodeStatePool.Put(e)
+}
+
 func (e *encodeState) marshal(v interface{}) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -285,7 +326,10 @@ func (e *encodeState) error(err error) {
 	panic(err)
 }
 
-var byteSliceType = reflect.TypeOf([]byte(nil))
+var (
+	byteSliceType = reflect.TypeOf([]byte(nil))
+	timeType      = reflect.TypeOf(time.Time{})
+)
 
 func isEmptyValue(v reflect.Value) bool {
 	switch v.Kind() {
@@ -366,6 +410,9 @@ var (
 // newTypeEncoder constructs an encoderFunc for a type.
 // The returned encoder only checks CanAddr when allowAddr is true.
 func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	if t == timeType || t == reflect.PtrTo(timeType) {
+		return timeEncoder
+	}
 	if t.Implements(simpleMarshalerType) {
 		return simpleMarshalerEncoder
 	}
@@ -424,6 +471,15 @@ func invalidValueEncoder(e *encodeState, v reflect.Value, quoted bool) {
 	e.WriteString("null")
 }
 
+// timeEncoder encodes time.Time values with the TimeFormat of the Options, if any.
+func timeEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	if e.opts.TimeFormat == "" || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		simpleMarshalerEncoder(e, v, quoted)
+		return
+	}
+	e.string(reflect.Indirect(v).Interface().(time.Time).Format(e.opts.TimeFormat))
+}
+
 func simpleMarshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
 	if v.Kind() == reflect.Ptr && v.IsNil() {
 		e.WriteString("null")
@@ -462,6 +518,9 @@ func marshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
 		e.WriteString("null")
 		return
 	}
+	if e.encodeGenerated(v) {
+		return
+	}
 	m := v.Interface().(Marshaler)
 	b, err := m.MarshalJSON(e.args...)
 	if err == nil {
@@ -479,6 +538,9 @@ func addrMarshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
 		e.WriteString("null")
 		return
 	}
+	if e.encodeGenerated(va) {
+		return
+	}
 	m := va.Interface().(Marshaler)
 	b, err := m.MarshalJSON(e.args...)
 	if err == nil {
@@ -620,6 +682,10 @@ func (se *structEncoder) encode(e *encodeState, v reflect.Value, quoted bool) {
 	e.WriteByte('{')
 	first := true
 	for i, f := range se.fields {
+		name, ok := f.nameAt(e.opts.APIVersion)
+		if !ok {
+			continue
+		}
 		fv := fieldByIndex(v, f.index)
 		if !fv.IsValid() || f.omitEmpty && isEmptyValue(fv) {
 			continue
@@ -629,7 +695,7 @@ func (se *structEncoder) encode(e *encodeState, v reflect.Value, quoted bool) {
 		} else {
 			e.WriteByte(',')
 		}
-		e.string(f.name)
+		e.string(name)
 		e.WriteByte(':')
 		se.fieldEncs[i](e, fv, f.quoted)
 	}
@@ -644,10 +710,130 @@ func newStructEncoder(t reflect.Type) encoderFunc {
 	}
 	for i, f := range fields {
 		se.fieldEncs[i] = typeEncoder(typeByIndex(t, f.index))
+		if f.to != "" {
+			se.fieldEncs[i] = newJSONToEncoder(f.to, se.fieldEncs[i])
+		}
 	}
 	return se.encode
 }
 
+// newJSONToEncoder returns an encoder that coerces the output of enc
+// according to the jsonTo directive to.
+func newJSONToEncoder(to string, enc encoderFunc) encoderFunc {
+	switch to {
+	case JSONToString:
+		return func(e *encodeState, v reflect.Value, _ bool) {
+			b := e.scratchEncode(enc, v)
+			if b[0] == '"' || b[0] == 'n' {
+				e.Write(b)
+				return
+			}
+			e.stringBytes(b)
+		}
+	case JSONToInt, JSONToFloat:
+		return func(e *encodeState, v reflect.Value, _ bool) {
+			b := e.scratchEncode(enc, v)
+			if b[0] == 'n' {
+				e.Write(b)
+				return
+			}
+			num := b
+			if b[0] == '"' {
+				num, _ = unquoteBytes(b)
+			}
+			if to == JSONToFloat {
+				f, ok := parseJSONToFloat(num)
+				if !ok {
+					e.error(&UnsupportedValueError{v, string(b) + " is not a finite number"})
+				}
+				e.Write(strconv.AppendFloat(e.scratch[:0], f, 'g', -1, 64))
+				return
+			}
+			i, ok := parseJSONToInt(num)
+			if !ok {
+				e.error(&UnsupportedValueError{v, string(b) + " is not an integer in the range of int64"})
+			}
+			e.Write(strconv.AppendInt(e.scratch[:0], i, 10))
+		}
+	case JSONToBase64:
+		return func(e *encodeState, v reflect.Value, _ bool) {
+			if v = indirectValue(v); !v.IsValid() {
+				e.WriteString("null")
+				return
+			}
+			var b []byte
+			switch {
+			case v.Kind() == reflect.String:
+				b = []byte(v.String())
+			case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+				b = v.Bytes()
+			default:
+				e.error(&UnsupportedTypeError{v.Type()})
+			}
+			e.WriteByte('"')
+			e.WriteString(base64.StdEncoding.EncodeToString(b))
+			e.WriteByte('"')
+		}
+	case JSONToUnixMillis:
+		return func(e *encodeState, v reflect.Value, _ bool) {
+			if v = indirectValue(v); !v.IsValid() {
+				e.WriteString("null")
+				return
+			}
+			if v.Type() != timeType {
+				e.error(&UnsupportedTypeError{v.Type()})
+			}
+			t := v.Interface().(time.Time)
+			e.Write(strconv.AppendInt(e.scratch[:0], t.UnixNano()/int64(time.Millisecond), 10))
+		}
+	}
+	return func(e *encodeState, v reflect.Value, _ bool) {
+		e.error(&UnsupportedValueError{v, "unknown jsonTo directive " + strconv.Quote(to)})
+	}
+}
+
+// parseJSONToFloat parses num as a number for the float directive, which must be finite to be valid JSON.
+func parseJSONToFloat(num []byte) (f float64, ok bool) {
+	f, err := strconv.ParseFloat(string(num), 64)
+	return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// parseJSONToInt parses num as a number for the int and unix-millis directives, which must be an integer in the range of
+// int64. Integers are parsed as such, to keep their precision, and other numbers only accepted without a fraction.
+func parseJSONToInt(num []byte) (i int64, ok bool) {
+	if i, err := strconv.ParseInt(string(num), 10, 64); err == nil {
+		return i, true
+	}
+	f, ok := parseJSONToFloat(num)
+	// -2^63 is exact as a float64, but 2^63-1 is not, so the upper bound is exclusive
+	if !ok || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// scratchEncode returns the output of enc for v, encoded with the
+// args and options of e but without touching the buffer of e.
+func (e *encodeState) scratchEncode(enc encoderFunc, v reflect.Value) []byte {
+	scratch := newEncodeState()
+	defer putEncodeState(scratch)
+	scratch.setArgs(e.args)
+	enc(scratch, v, false)
+	return append([]byte{}, scratch.Bytes()...)
+}
+
+// indirectValue follows pointers and interfaces of v, and returns
+// the zero Value if it finds a nil.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
 type mapEncoder struct {
 	elemEnc encoderFunc
 }
@@ -988,6 +1174,28 @@ type field struct {
 	typ       reflect.Type
 	omitEmpty bool
 	quoted    bool
+	to        string // jsonTo directive
+	versions  FieldVersions
+	hidden    []versionRange // API versions where another field has the same name and dominates f
+}
+
+// versionRange is the API versions from from to to, or on from from if to is negative.
+type versionRange struct {
+	from, to int
+}
+
+func (r versionRange) contains(version int) bool {
+	return version >= r.from && (r.to < 0 || version <= r.to)
+}
+
+// hiddenAt returns whether another field dominates f in the API version.
+func (f *field) hiddenAt(version int) bool {
+	for _, r := range f.hidden {
+		if r.contains(version) {
+			return true
+		}
+	}
+	return false
 }
 
 func fillField(f field) field {
@@ -996,6 +1204,47 @@ func fillField(f field) field {
 	return f
 }
 
+// nameAt returns the name of f in the API version, and whether f is included in it.
+func (f *field) nameAt(version int) (string, bool) {
+	if f.hidden != nil && f.hiddenAt(version) {
+		return "", false
+	}
+	return f.versions.Name(f.name, version)
+}
+
+// matches returns whether key is the name of f in the API version, and if not whether it matches case-insensitively.
+func (f *field) matches(key []byte, version int) (exact, folded bool) {
+	if (version == 0 || !f.versions.Versioned()) && f.hidden == nil {
+		if bytes.Equal(f.nameBytes, key) {
+			return true, true
+		}
+		return false, f.equalFold(f.nameBytes, key)
+	}
+	name, ok := f.nameAt(version)
+	if !ok {
+		return false, false
+	}
+	if name == string(key) {
+		return true, true
+	}
+	return false, bytes.EqualFold([]byte(name), key)
+}
+
+// lookupField returns the field named key in the API version, preferring an exact match over a case-insensitive one.
+func lookupField(fields []field, key []byte, version int) (f *field) {
+	for i := range fields {
+		ff := &fields[i]
+		exact, folded := ff.matches(key, version)
+		if exact {
+			return ff
+		}
+		if f == nil && folded {
+			f = ff
+		}
+	}
+	return
+}
+
 // byName sorts field by name, breaking ties with depth,
 // then breaking ties with "name came from json tag", then
 // breaking ties with index sequence.
@@ -1102,6 +1351,8 @@ func typeFields(t reflect.Type) []field {
 						typ:       ft,
 						omitEmpty: opts.Contains("omitempty"),
 						quoted:    opts.Contains("string"),
+						to:        sf.Tag.Get("jsonTo"),
+						versions:  Versions(sf),
 					}))
 					if count[f.typ] > 1 {
 						// If there were multiple instances, add a second,
@@ -1122,40 +1373,91 @@ func typeFields(t reflect.Type) []field {
 		}
 	}
 
-	sort.Sort(byName(fields))
+	// Fields with version tags only conflict with the fields that have the same name in the same API versions, so the
+	// conflicts are resolved in each range of versions where the fields and their names don't change, and version 0, where
+	// all fields are included with the names in their json tags.
+	versions := []int{0, 1}
+	for _, f := range fields {
+		versions = append(versions, f.versions.Changes()...)
+	}
+	sort.Ints(versions)
+	dominates := make([]bool, len(fields))
+	for i, version := range versions {
+		if i > 0 && version == versions[i-1] {
+			continue
+		}
+		r := versionRange{from: version, to: -1}
+		for _, next := range versions[i+1:] {
+			if next > version {
+				r.to = next - 1
+				break
+			}
+		}
+		for _, hidden := range hiddenFields(fields, version) {
+			if hidden >= 0 {
+				fields[hidden].hidden = append(fields[hidden].hidden, r)
+			}
+		}
+		for j := range fields {
+			if _, ok := fields[j].nameAt(version); ok {
+				dominates[j] = true
+			}
+		}
+	}
+
+	out := fields[:0]
+	for i, f := range fields {
+		if dominates[i] {
+			out = append(out, f)
+		}
+	}
+	fields = out
+	sort.Sort(byIndex(fields))
 
-	// Delete all fields that are hidden by the Go rules for embedded fields,
-	// except that fields with JSON tags are promoted.
+	return fields
+}
 
+// hiddenFields returns the indices in fields of those included in the API version that are hidden by the Go rules for
+// embedded fields, except that fields with JSON tags are promoted, among the fields with the same name in the version.
+func hiddenFields(fields []field, version int) (result []int) {
 	// The fields are sorted in primary order of name, secondary order
-	// of field index length. Loop over names; for each name, delete
-	// hidden fields by choosing the one dominant field that survives.
-	out := fields[:0]
-	for advance, i := 0, 0; i < len(fields); i += advance {
+	// of field index length. Loop over names; for each name, hide
+	// the fields other than the one dominant field that survives.
+	included := []field{}
+	positions := map[*int][]int{} // duplicated fields share their index
+	for i := range fields {
+		name, ok := fields[i].nameAt(version)
+		if !ok {
+			continue
+		}
+		f := fields[i]
+		f.name = name
+		included = append(included, f)
+		positions[&f.index[0]] = append(positions[&f.index[0]], i)
+	}
+	sort.Sort(byName(included))
+
+	for advance, i := 0, 0; i < len(included); i += advance {
 		// One iteration per name.
 		// Find the sequence of fields with the name of this first field.
-		fi := fields[i]
-		name := fi.name
-		for advance = 1; i+advance < len(fields); advance++ {
-			fj := fields[i+advance]
-			if fj.name != name {
+		name := included[i].name
+		for advance = 1; i+advance < len(included); advance++ {
+			if included[i+advance].name != name {
 				break
 			}
 		}
 		if advance == 1 { // Only one field with this name
-			out = append(out, fi)
 			continue
 		}
-		dominant, ok := dominantField(fields[i : i+advance])
-		if ok {
-			out = append(out, dominant)
+		dominant, ok := dominantField(append([]field{}, included[i:i+advance]...))
+		for _, f := range included[i : i+advance] {
+			if !ok || &f.index[0] != &dominant.index[0] {
+				result = append(result, positions[&f.index[0]]...)
+				delete(positions, &f.index[0])
+			}
 		}
 	}
-
-	fields = out
-	sort.Sort(byIndex(fields))
-
-	return fields
+	return
 }
 
 // dominantField looks through the fields, all of which are known to
@@ -6,9 +6,11 @@ package json
 
 import (
 	"bytes"
+	"io/ioutil"
 	"math"
 	"reflect"
 	"testing"
+	"time"
 	"unicode"
 )
 
@@ -452,3 +454,97 @@ func TestHTMLEscape(t *testing.T) {
 		t.Errorf("HTMLEscape(&b, []byte(m)) = %s; want %s", b.Bytes(), want.Bytes())
 	}
 }
+
+type JSONToStruct struct {
+	S  int        `jsonTo:"string"`
+	SS string     `jsonTo:"string"`
+	I  string     `jsonTo:"int"`
+	F  string     `jsonTo:"float"`
+	B  string     `jsonTo:"base64"`
+	BB []byte     `jsonTo:"base64"`
+	T  time.Time  `jsonTo:"unix-millis"`
+	TP *time.Time `jsonTo:"unix-millis"`
+}
+
+func TestJSONTo(t *testing.T) {
+	s := JSONToStruct{
+		S:  12,
+		SS: "hello",
+		I:  "42",
+		F:  "1.5",
+		B:  "hello",
+		BB: []byte("world"),
+		T:  time.Unix(1400000000, 123000000),
+	}
+	b, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"S":"12","SS":"hello","I":42,"F":1.5,"B":"aGVsbG8=","BB":"d29ybGQ=","T":1400000000123,"TP":null}`; string(b) != want {
+		t.Errorf("Marshal(s) = %s; want %s", b, want)
+	}
+
+	type BadDirective struct {
+		X int `jsonTo:"hex"`
+	}
+	if _, err := Marshal(BadDirective{}); err == nil {
+		t.Errorf("Marshal with unknown jsonTo directive should fail")
+	}
+	type BadType struct {
+		X int `jsonTo:"unix-millis"`
+	}
+	if _, err := Marshal(BadType{}); err == nil {
+		t.Errorf("Marshal of int with unix-millis directive should fail")
+	}
+
+	if b, err := Marshal(JSONToStruct{I: "9007199254740993", F: "1e30"}); err != nil || !bytes.Contains(b, []byte(`"I":9007199254740993,"F":1e+30,`)) {
+		t.Errorf("Wanted large numbers to keep their precision, got %s, %v", b, err)
+	}
+	for _, s := range []JSONToStruct{{I: "7.9", F: "0"}, {I: "1e30", F: "0"}, {I: "NaN", F: "0"}, {I: "0", F: "NaN"}, {I: "0", F: "Inf"}, {I: "0", F: "-Inf"}} {
+		if b, err := Marshal(s); err == nil {
+			t.Errorf("Marshal of %+v should fail, got %s", s, b)
+		} else if _, ok := err.(*UnsupportedValueError); !ok {
+			t.Errorf("Marshal of %+v should fail with an *UnsupportedValueError, got %#v", s, err)
+		}
+	}
+}
+
+func TestAppendMarshal(t *testing.T) {
+	buf := []byte("prefix ")
+	buf, err := AppendMarshal(buf, map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := buf
+	buf, err = AppendMarshal(buf, []string{"<b>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `prefix {"a":1}["\u003cb\u003e"]`; string(buf) != want {
+		t.Errorf("AppendMarshal = %s; want %s", buf, want)
+	}
+	if want := `prefix {"a":1}`; string(first) != want {
+		t.Errorf("AppendMarshal modified earlier result to %s; want %s", first, want)
+	}
+
+	b1, _ := Marshal("first")
+	b2, _ := Marshal("second")
+	if string(b1) != `"first"` || string(b2) != `"second"` {
+		t.Errorf("Marshal results share buffers: %s, %s", b1, b2)
+	}
+
+	if _, err := AppendMarshal(nil, make(chan int)); err == nil {
+		t.Errorf("AppendMarshal of a channel should fail")
+	}
+}
+
+func BenchmarkEncoderReuse(b *testing.B) {
+	enc := NewEncoder(ioutil.Discard)
+	row := map[string]interface{}{"name": "row", "count": 12, "ratio": 0.5}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := enc.Encode(row); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
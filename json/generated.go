@@ -0,0 +1,425 @@
+package json
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"runtime"
+	"strconv"
+)
+
+/*
+GeneratedMarshaler is implemented by pointers to the types that jsongen has generated methods for.
+
+Marshal lets them encode themselves straight into its buffer, instead of copying and validating the output of MarshalJSON.
+*/
+type GeneratedMarshaler interface {
+	EncodeJSON(enc *GeneratedEncoder)
+}
+
+/*
+GeneratedUnmarshaler is implemented by pointers to the types that jsongen has generated methods for.
+
+Unmarshal lets them decode themselves straight from its input, instead of cutting out their JSON and calling UnmarshalJSON.
+
+The generated methods only replace the reflective decoding, so LoadJSON, ApplyPatch and ApplyMergePatch still load
+GeneratedUnmarshalers field by field to enforce their scope tags.
+*/
+type GeneratedUnmarshaler interface {
+	DecodeJSON(dec *GeneratedDecoder)
+}
+
+var generatedUnmarshalerType = reflect.TypeOf((*GeneratedUnmarshaler)(nil)).Elem()
+
+/*
+MarshalGenerated is used by the MarshalJSON methods generated by jsongen to encode g with the args.
+*/
+func MarshalGenerated(g GeneratedMarshaler, args ...interface{}) (result []byte, err error) {
+	e := newEncodeState()
+	defer putEncodeState(e)
+	e.setArgs(args)
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if s, ok := r.(string); ok {
+				panic(s)
+			}
+			err = r.(error)
+		}
+	}()
+	g.EncodeJSON(e.generatedEncoder())
+	return append([]byte(nil), e.Bytes()...), nil
+}
+
+// encodeGenerated lets v encode itself if it is, or is addressable as, a GeneratedMarshaler, and returns whether it did.
+func (e *encodeState) encodeGenerated(v reflect.Value) bool {
+	if v.Kind() != reflect.Ptr {
+		if !v.CanAddr() {
+			return false
+		}
+		v = v.Addr()
+	}
+	if g, ok := v.Interface().(GeneratedMarshaler); ok {
+		g.EncodeJSON(e.generatedEncoder())
+		return true
+	}
+	return false
+}
+
+func (e *encodeState) generatedEncoder() *GeneratedEncoder {
+	e.generated.e = e
+	return &e.generated
+}
+
+/*
+GeneratedEncoder is used by the EncodeJSON methods generated by jsongen to encode JSON objects the same way Marshal does.
+
+Nested objects share the encoder of their encodeState.
+*/
+type GeneratedEncoder struct {
+	e *encodeState
+	// empty is true between the start of an object and its first member.
+	empty bool
+}
+
+// Begin starts an object.
+func (self *GeneratedEncoder) Begin() {
+	self.e.WriteByte('{')
+	self.empty = true
+}
+
+// End ends the object.
+func (self *GeneratedEncoder) End() {
+	self.e.WriteByte('}')
+	self.empty = false
+}
+
+// Key writes the key of the next member of the object.
+func (self *GeneratedEncoder) Key(name string) {
+	if self.empty {
+		self.empty = false
+	} else {
+		self.e.WriteByte(',')
+	}
+	self.e.string(name)
+	self.e.WriteByte(':')
+}
+
+func (self *GeneratedEncoder) String(s string, quoted bool) {
+	if quoted {
+		sb, err := Marshal(s)
+		if err != nil {
+			self.e.error(err)
+		}
+		s = string(sb)
+	}
+	self.e.string(s)
+}
+
+func (self *GeneratedEncoder) Bool(b bool, quoted bool) {
+	if quoted {
+		self.e.WriteByte('"')
+	}
+	if b {
+		self.e.WriteString("true")
+	} else {
+		self.e.WriteString("false")
+	}
+	if quoted {
+		self.e.WriteByte('"')
+	}
+}
+
+func (self *GeneratedEncoder) Int(i int64, quoted bool) {
+	self.number(strconv.AppendInt(self.e.scratch[:0], i, 10), quoted)
+}
+
+func (self *GeneratedEncoder) Uint(u uint64, quoted bool) {
+	self.number(strconv.AppendUint(self.e.scratch[:0], u, 10), quoted)
+}
+
+func (self *GeneratedEncoder) Float(f float64, bits int, quoted bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		self.e.error(&UnsupportedValueError{reflect.ValueOf(f), strconv.FormatFloat(f, 'g', -1, bits)})
+	}
+	self.number(strconv.AppendFloat(self.e.scratch[:0], f, 'g', -1, bits), quoted)
+}
+
+func (self *GeneratedEncoder) number(b []byte, quoted bool) {
+	if quoted {
+		self.e.WriteByte('"')
+	}
+	self.e.Write(b)
+	if quoted {
+		self.e.WriteByte('"')
+	}
+}
+
+/*
+Value encodes the value p points to using the reflective encoder, honouring the ',string' option if quoted and the jsonTo directive to.
+*/
+func (self *GeneratedEncoder) Value(p interface{}, quoted bool, to string) {
+	v := reflect.ValueOf(p).Elem()
+	enc := typeEncoder(v.Type())
+	if to != "" {
+		enc = newJSONToEncoder(to, enc)
+	}
+	enc(self.e, v, quoted)
+}
+
+// IsEmpty returns whether the value p points to is empty as defined by the 'omitempty' option.
+func IsEmpty(p interface{}) bool {
+	return isEmptyValue(reflect.ValueOf(p).Elem())
+}
+
+/*
+UnmarshalGenerated is used by the UnmarshalJSON methods generated by jsongen to decode data into g with the args.
+*/
+func UnmarshalGenerated(data []byte, g GeneratedUnmarshaler, args ...interface{}) (err error) {
+	d := &decodeState{}
+	d.setArgs(args)
+	if err = checkValid(data, &d.scan); err != nil {
+		return
+	}
+	d.init(data)
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+	d.scan.reset()
+	switch d.scanWhile(scanSkipSpace) {
+	case scanBeginObject:
+		g.DecodeJSON(d.generatedDecoder())
+	case scanBeginArray:
+		d.saveError(&UnmarshalTypeError{"array", reflect.TypeOf(g).Elem()})
+	default:
+		switch bytes.TrimSpace(data)[0] {
+		case 'n':
+		case '"':
+			d.saveError(&UnmarshalTypeError{"string", reflect.TypeOf(g).Elem()})
+		case 't', 'f':
+			d.saveError(&UnmarshalTypeError{"bool", reflect.TypeOf(g).Elem()})
+		default:
+			d.saveError(&UnmarshalTypeError{"number", reflect.TypeOf(g).Elem()})
+		}
+	}
+	return d.savedError
+}
+
+func (d *decodeState) generatedDecoder() *GeneratedDecoder {
+	d.generated.d = d
+	return &d.generated
+}
+
+/*
+GeneratedDecoder is used by the DecodeJSON methods generated by jsongen to decode JSON objects the same way Unmarshal does.
+
+The generated code calls Next to read the key of each member of the object, and then decodes the value with Value or Skip.
+Nested objects share the decoder of their decodeState.
+*/
+type GeneratedDecoder struct {
+	d   *decodeState
+	key []byte
+}
+
+// Next reads the key of the next member of the object, and returns false at the end of the object.
+func (self *GeneratedDecoder) Next() bool {
+	d := self.d
+	// Read the , after the previous value, or the } ending the object.
+	op := d.scanWhile(scanSkipSpace)
+	if op == scanEndObject {
+		return false
+	}
+	if op == scanObjectValue {
+		op = d.scanWhile(scanSkipSpace)
+	}
+	if op != scanBeginLiteral {
+		d.error(errPhase)
+	}
+
+	// Read key.
+	start := d.off - 1
+	op = d.scanWhile(scanContinue)
+	item := d.data[start : d.off-1]
+	key, ok := unquoteBytes(item)
+	if !ok {
+		d.error(errPhase)
+	}
+	self.key = key
+
+	// Read : before value.
+	if op == scanSkipSpace {
+		op = d.scanWhile(scanSkipSpace)
+	}
+	if op != scanObjectKey {
+		d.error(errPhase)
+	}
+	return true
+}
+
+// Match returns the index of the name in names that the current key matches, preferring exact matches to case insensitive ones like Unmarshal does, or -1.
+func (self *GeneratedDecoder) Match(names []string) int {
+	for index, name := range names {
+		if string(self.key) == name {
+			return index
+		}
+	}
+	for index, name := range names {
+		if bytes.EqualFold(self.key, []byte(name)) {
+			return index
+		}
+	}
+	return -1
+}
+
+// Skip skips the current value.
+func (self *GeneratedDecoder) Skip() {
+	self.d.value(reflect.Value{})
+}
+
+/*
+Value decodes the current value into the field p points to, honouring the ',string' option if quoted and the jsonTo directive to.
+
+Strings, booleans and numbers are decoded without reflection when possible.
+*/
+func (self *GeneratedDecoder) Value(p interface{}, quoted bool, to string) {
+	d := self.d
+	if quoted {
+		d.value(reflect.ValueOf(&d.tempstr))
+		d.literalStore([]byte(d.tempstr), reflect.ValueOf(p).Elem(), true)
+		d.tempstr = "" // Zero scratch space for successive values.
+		return
+	}
+	if to != "" {
+		d.jsonToValue(reflect.ValueOf(p).Elem(), to)
+		return
+	}
+	if !isBasicPointer(p) {
+		d.value(reflect.ValueOf(p).Elem())
+		return
+	}
+	switch op := d.scanWhile(scanSkipSpace); op {
+	case scanBeginLiteral:
+		// All bytes inside literal return scanContinue op code.
+		start := d.off - 1
+		op = d.scanWhile(scanContinue)
+
+		// Scan read one byte too far; back up.
+		d.off--
+		d.scan.undo(op)
+
+		item := d.data[start:d.off]
+		if !storeBasic(item, p) {
+			// Let the reflective decoder produce the same errors as for reflectively decoded structs.
+			d.literalStore(item, reflect.ValueOf(p).Elem(), false)
+		}
+	case scanBeginArray:
+		d.array(reflect.ValueOf(p).Elem())
+	case scanBeginObject:
+		d.object(reflect.ValueOf(p).Elem())
+	default:
+		d.error(errPhase)
+	}
+}
+
+func isBasicPointer(p interface{}) bool {
+	switch p.(type) {
+	case *string, *bool, *int, *int8, *int16, *int32, *int64, *uint, *uint8, *uint16, *uint32, *uint64, *float32, *float64:
+		return true
+	}
+	return false
+}
+
+// storeBasic stores the literal item in p, and returns false if it can't do it without reflection.
+func storeBasic(item []byte, p interface{}) bool {
+	if item[0] == 'n' {
+		return false
+	}
+	switch p := p.(type) {
+	case *string:
+		if s, ok := unquote(item); ok {
+			*p = s
+			return true
+		}
+	case *bool:
+		switch string(item) {
+		case "true":
+			*p = true
+			return true
+		case "false":
+			*p = false
+			return true
+		}
+	case *int:
+		if i, err := strconv.ParseInt(string(item), 10, strconv.IntSize); err == nil {
+			*p = int(i)
+			return true
+		}
+	case *int8:
+		if i, err := strconv.ParseInt(string(item), 10, 8); err == nil {
+			*p = int8(i)
+			return true
+		}
+	case *int16:
+		if i, err := strconv.ParseInt(string(item), 10, 16); err == nil {
+			*p = int16(i)
+			return true
+		}
+	case *int32:
+		if i, err := strconv.ParseInt(string(item), 10, 32); err == nil {
+			*p = int32(i)
+			return true
+		}
+	case *int64:
+		if i, err := strconv.ParseInt(string(item), 10, 64); err == nil {
+			*p = i
+			return true
+		}
+	case *uint:
+		if u, err := strconv.ParseUint(string(item), 10, strconv.IntSize); err == nil {
+			*p = uint(u)
+			return true
+		}
+	case *uint8:
+		if u, err := strconv.ParseUint(string(item), 10, 8); err == nil {
+			*p = uint8(u)
+			return true
+		}
+	case *uint16:
+		if u, err := strconv.ParseUint(string(item), 10, 16); err == nil {
+			*p = uint16(u)
+			return true
+		}
+	case *uint32:
+		if u, err := strconv.ParseUint(string(item), 10, 32); err == nil {
+			*p = uint32(u)
+			return true
+		}
+	case *uint64:
+		if u, err := strconv.ParseUint(string(item), 10, 64); err == nil {
+			*p = u
+			return true
+		}
+	case *float32:
+		if item[0] != '"' {
+			if f, err := strconv.ParseFloat(string(item), 32); err == nil {
+				*p = float32(f)
+				return true
+			}
+		}
+	case *float64:
+		if item[0] != '"' {
+			if f, err := strconv.ParseFloat(string(item), 64); err == nil {
+				*p = f
+				return true
+			}
+		}
+	}
+	return false
+}
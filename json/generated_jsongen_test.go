@@ -0,0 +1,99 @@
+// Code generated by "jsongen -type=genTagged"; DO NOT EDIT.
+
+package json
+
+var jsonKeysGenTagged = []string{
+	"id",
+	"label",
+	"name",
+	"count",
+	"ratio",
+	"flag",
+	"small",
+	"when",
+	"tags",
+	"echo",
+	"Plain",
+}
+
+func (self genTagged) MarshalJSON(args ...interface{}) ([]byte, error) {
+	return MarshalGenerated(&self, args...)
+}
+
+func (self *genTagged) EncodeJSON(enc *GeneratedEncoder) {
+	enc.Begin()
+	if self.GenMeta != nil {
+		enc.Key("id")
+		enc.String(self.GenMeta.Id, false)
+	}
+	if self.GenMeta != nil && self.GenMeta.Label != "" {
+		enc.Key("label")
+		enc.String(self.GenMeta.Label, false)
+	}
+	if self.Name != "" {
+		enc.Key("name")
+		enc.String(self.Name, false)
+	}
+	enc.Key("count")
+	enc.Int(int64(self.Count), true)
+	if self.Ratio != 0 {
+		enc.Key("ratio")
+		enc.Float(float64(self.Ratio), 32, false)
+	}
+	enc.Key("flag")
+	enc.Bool(self.Flag, true)
+	enc.Key("small")
+	enc.Uint(uint64(self.Small), false)
+	enc.Key("when")
+	enc.Value(&self.When, false, "unix-millis")
+	if !IsEmpty(&self.Tags) {
+		enc.Key("tags")
+		enc.Value(&self.Tags, false, "")
+	}
+	enc.Key("echo")
+	enc.Value(&self.Echo, false, "")
+	enc.Key("Plain")
+	enc.Int(int64(self.Plain), false)
+	enc.End()
+}
+
+func (self *genTagged) UnmarshalJSON(data []byte, args ...interface{}) error {
+	return UnmarshalGenerated(data, self, args...)
+}
+
+func (self *genTagged) DecodeJSON(dec *GeneratedDecoder) {
+	for dec.Next() {
+		switch dec.Match(jsonKeysGenTagged) {
+		case 0:
+			if self.GenMeta == nil {
+				self.GenMeta = new(GenMeta)
+			}
+			dec.Value(&self.GenMeta.Id, false, "")
+		case 1:
+			if self.GenMeta == nil {
+				self.GenMeta = new(GenMeta)
+			}
+			dec.Value(&self.GenMeta.Label, false, "")
+		case 2:
+			dec.Value(&self.Name, false, "")
+		case 3:
+			dec.Value(&self.Count, true, "")
+		case 4:
+			dec.Value(&self.Ratio, false, "")
+		case 5:
+			dec.Value(&self.Flag, true, "")
+		case 6:
+			dec.Value(&self.Small, false, "")
+		case 7:
+			dec.Value(&self.When, false, "unix-millis")
+		case 8:
+			dec.Value(&self.Tags, false, "")
+		case 9:
+			dec.Value(&self.Echo, false, "")
+		case 10:
+			dec.Value(&self.Plain, false, "")
+		default:
+			dec.Skip()
+		}
+	}
+}
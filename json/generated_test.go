@@ -0,0 +1,141 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//go:generate go run jsongen/main.go -type=genTagged -output=generated_jsongen_test.go
+
+// argEcho encodes as the args it is marshalled with, and decodes into the args it is unmarshalled with.
+type argEcho string
+
+func (self argEcho) MarshalJSON(args ...interface{}) ([]byte, error) {
+	return Marshal(fmt.Sprint(args...))
+}
+
+func (self *argEcho) UnmarshalJSON(b []byte, args ...interface{}) error {
+	*self = argEcho(fmt.Sprint(args...))
+	return nil
+}
+
+type GenMeta struct {
+	Id    string `json:"id"`
+	Label string `json:"label,omitempty" PUT_scopes:"user"`
+}
+
+type genTagged struct {
+	*GenMeta
+	Name    string            `json:"name,omitempty" PUT_scopes:"user"`
+	Count   int               `json:"count,string"`
+	Ratio   float32           `json:"ratio,omitempty"`
+	Flag    bool              `json:"flag,string"`
+	Small   uint8             `json:"small"`
+	When    time.Time         `json:"when" jsonTo:"unix-millis"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Echo    argEcho           `json:"echo"`
+	Skipped string            `json:"-"`
+	Plain   int
+}
+
+// reflectTagged has the same fields as genTagged, but is encoded reflectively.
+type reflectTagged struct {
+	*GenMeta
+	Name    string            `json:"name,omitempty" PUT_scopes:"user"`
+	Count   int               `json:"count,string"`
+	Ratio   float32           `json:"ratio,omitempty"`
+	Flag    bool              `json:"flag,string"`
+	Small   uint8             `json:"small"`
+	When    time.Time         `json:"when" jsonTo:"unix-millis"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Echo    argEcho           `json:"echo"`
+	Skipped string            `json:"-"`
+	Plain   int
+}
+
+func TestGeneratedMarshal(t *testing.T) {
+	when := time.Unix(1400000000, 123000000).UTC()
+	for _, r := range []reflectTagged{
+		{},
+		{
+			GenMeta: &GenMeta{Id: "id", Label: "<label>"},
+			Name:    "name",
+			Count:   12,
+			Ratio:   0.5,
+			Flag:    true,
+			Small:   255,
+			When:    when,
+			Tags:    map[string]string{"a": "b"},
+			Skipped: "skipped",
+			Plain:   -3,
+		},
+	} {
+		want, err := Marshal(r, "x", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := genTagged(r)
+		for _, v := range []interface{}{g, &g, []genTagged{g}} {
+			got, err := Marshal(v, "x", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := v.([]genTagged); ok {
+				got = got[1 : len(got)-1]
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Wanted %s, got %s", want, got)
+			}
+		}
+
+		var rDecoded reflectTagged
+		if err := Unmarshal(want, &rDecoded, "y"); err != nil {
+			t.Fatal(err)
+		}
+		var gDecoded genTagged
+		if err := Unmarshal(want, &gDecoded, "y"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(genTagged(rDecoded), gDecoded) {
+			t.Errorf("Wanted %+v, got %+v", rDecoded, gDecoded)
+		}
+	}
+}
+
+func TestGeneratedUnmarshalErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"count":12}`,
+		`{"small":256}`,
+		`{"name":1}`,
+		`"string"`,
+		`[1]`,
+		`{"name":"x",}`,
+	} {
+		var r reflectTagged
+		rErr := Unmarshal([]byte(data), &r)
+		var g genTagged
+		gErr := Unmarshal([]byte(data), &g)
+		if strings.Replace(fmt.Sprint(rErr), "reflectTagged", "genTagged", -1) != fmt.Sprint(gErr) {
+			t.Errorf("%v: wanted %v, got %v", data, rErr, gErr)
+		}
+		if gErr2 := g.UnmarshalJSON([]byte(data)); (gErr2 == nil) != (gErr == nil) {
+			t.Errorf("%v: wanted %v from UnmarshalJSON, got %v", data, gErr, gErr2)
+		}
+	}
+}
+
+func TestGeneratedLoadJSON(t *testing.T) {
+	g := &genTagged{GenMeta: &GenMeta{Id: "id"}}
+	err := LoadJSON(bytes.NewBufferString(`{"id":"other","label":"label","name":"name"}`), g, "PUT", "user")
+	scopeErr, ok := err.(*ScopeError)
+	if !ok || !reflect.DeepEqual(scopeErr.Fields, []string{"/id"}) {
+		t.Fatalf("Wanted /id to be rejected, got %v", err)
+	}
+	if g.Id != "id" || g.Label != "label" || g.Name != "name" {
+		t.Fatalf("Wrong load, got %+v", g)
+	}
+}
@@ -5,9 +5,25 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strings"
 )
 
+/*
+ScopeError is returned when a document tries to update fields that the provided access scopes are not allowed to update.
+*/
+type ScopeError struct {
+	// Fields contains JSON Pointers to the rejected fields.
+	Fields []string
+}
+
+func (self *ScopeError) Error() string {
+	return fmt.Sprintf("json: not allowed to update %v", strings.Join(self.Fields, ", "))
+}
+
+/*
+CopyJSON will JSON encode in and load it into out using LoadJSON.
+*/
 func CopyJSON(in interface{}, out interface{}, context string, accessScopes ...string) (err error) {
 	buf := &bytes.Buffer{}
 	if err = NewEncoder(buf).Encode(in); err != nil {
@@ -18,68 +34,148 @@ func CopyJSON(in interface{}, out interface{}, context string, accessScopes ...s
 }
 
 /*
-LoadJSON will JSON decode in into out, but only the fields of out that have a tag 'update_scopes' matching the provided accessScopes or '*'.
+LoadJSON will JSON decode in into out, but only the fields of out that have a tag '<context>_scopes' matching the provided accessScopes or '*'.
+
+Objects are decoded into nested and embedded structs and maps field by field, and fields without a scopes tag inherit the
+permission of the field containing them. Fields of the elements of slices are checked against their own scopes tags.
+
+Fields that are null in in are left untouched, in or out of scope, and so is out if in is null. Values for fields out of scope
+that would not change the field are ignored. If in tries to change any other fields out of scope, the allowed fields are still
+loaded into out, and a *ScopeError naming the rejected fields is returned.
 */
 func LoadJSON(in io.Reader, out interface{}, context string, accessScopes ...string) (err error) {
-	var decodedJSON map[string]*RawMessage
-	if err = NewDecoder(in).Decode(&decodedJSON); err != nil {
+	return LoadJSONOptions(in, out, Options{
+		Context: context,
+		Scopes:  accessScopes,
+	})
+}
+
+/*
+LoadJSONOptions is like LoadJSON with the Context and Scopes of opts, but also only loads the fields included in the
+APIVersion of opts, under their names in that version.
+*/
+func LoadJSONOptions(in io.Reader, out interface{}, opts Options) (err error) {
+	var data RawMessage
+	if err = NewDecoder(in).Decode(&data); err != nil {
 		return
 	}
 
 	structPointerValue := reflect.ValueOf(out)
-	if structPointerValue.Kind() != reflect.Ptr {
+	if structPointerValue.Kind() != reflect.Ptr || structPointerValue.IsNil() || structPointerValue.Elem().Kind() != reflect.Struct {
 		err = fmt.Errorf("%#v is not a pointer to a struct", out)
 		return
 	}
-	structValue := structPointerValue.Elem()
-	if structValue.Kind() != reflect.Struct {
-		err = fmt.Errorf("%#v is not a pointer to a struct.", out)
+	if string(data) == "null" {
+		return
+	}
+	if data[0] != '{' {
+		err = &UnmarshalTypeError{Value: "non-object", Type: structPointerValue.Elem().Type()}
+		return
+	}
+	p := &patcher{
+		context:      opts.Context,
+		accessScopes: opts.Scopes,
+		apiVersion:   opts.APIVersion,
+		load:         true,
+	}
+	if err = p.merge(structPointerValue.Elem(), data, false, ""); err != nil {
 		return
 	}
+	return p.scopeError()
+}
 
-	structType := structValue.Type()
-	for i := 0; i < structValue.NumField(); i++ {
-		valueField := structValue.Field(i)
-		typeField := structType.Field(i)
+/*
+Field describes how Marshal and Unmarshal encode a struct field.
+*/
+type Field struct {
+	// Name is the key of the field in JSON objects.
+	Name string
+	// Index is the index sequence of the field for reflect.Type.FieldByIndex, through any embedded structs.
+	Index []int
+	Type  reflect.Type
+	// OmitEmpty is true if the field has the 'omitempty' option.
+	OmitEmpty bool
+	// Quoted is true if the field has the 'string' option.
+	Quoted bool
+	// To is the jsonTo directive of the field.
+	To string
+	// Versions describe in which API versions the field is included, and under which names.
+	Versions FieldVersions
 
-		updateScopesTag := typeField.Tag.Get(context + "_scopes")
-		allowedScopes := strings.Split(updateScopesTag, ",")
-		jsonAttributeName := typeField.Name
-		if jsonTag := typeField.Tag.Get("json"); jsonTag != "" {
-			jsonAttributeName = strings.Split(jsonTag, ",")[0]
-		}
+	hidden []versionRange
+}
 
-		// Newer try to update field '-'
-		if jsonAttributeName == "-" {
-			continue
-		}
+/*
+NameAt returns the key of the field in JSON objects in the API version, and whether Marshal and Unmarshal include the field
+in it. Unlike Versions.Name, it takes other fields with the same name in the version into account.
+*/
+func (self Field) NameAt(version int) (string, bool) {
+	f := field{name: self.Name, versions: self.Versions, hidden: self.hidden}
+	return f.nameAt(version)
+}
 
-		// Check if a update for this field exist in the source json data.
-		data, found := decodedJSON[jsonAttributeName]
-		if !found || data == nil {
-			continue
-		}
+/*
+Fields returns the fields of the struct type t that Marshal and Unmarshal encode, in the order Marshal encodes them.
+*/
+func Fields(t reflect.Type) (result []Field) {
+	for _, f := range cachedTypeFields(t) {
+		result = append(result, Field{
+			Name:      f.name,
+			Index:     f.index,
+			Type:      t.FieldByIndex(f.index).Type,
+			OmitEmpty: f.omitEmpty,
+			Quoted:    f.quoted,
+			To:        f.to,
+			Versions:  f.versions,
+			hidden:    f.hidden,
+		})
+	}
+	return
+}
+
+/*
+APIVersions returns the API versions, in order, where fields of t or of any type reachable from it appear, disappear or are
+renamed. Between them, Marshal encodes t the same way.
+*/
+func APIVersions(t reflect.Type) (result []int) {
+	found := map[int]bool{}
+	apiVersions(t, map[reflect.Type]bool{}, found)
+	for version := range found {
+		result = append(result, version)
+	}
+	sort.Ints(result)
+	return
+}
 
-		// Check that at least one of the scopes is allowed to update this field.
-		inScope := updateScopesTag == "*"
-		if !inScope {
-			for _, scope := range accessScopes {
-				for _, allowedScope := range allowedScopes {
-					if scope == allowedScope {
-						inScope = true
-						break
-					}
-				}
+func apiVersions(t reflect.Type, seen map[reflect.Type]bool, found map[int]bool) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		apiVersions(t.Elem(), seen, found)
+	case reflect.Map:
+		apiVersions(t.Key(), seen, found)
+		apiVersions(t.Elem(), seen, found)
+	case reflect.Struct:
+		for _, f := range cachedTypeFields(t) {
+			for _, version := range f.versions.Changes() {
+				found[version] = true
 			}
+			apiVersions(t.FieldByIndex(f.index).Type, seen, found)
 		}
-		if !inScope {
-			continue
-		}
+	}
+}
 
-		// Use json unmarshal the raw value in to correct field.
-		if err = Unmarshal(*data, valueField.Addr().Interface()); err != nil {
-			return
-		}
+/*
+FieldAllowed returns whether the '<context>_scopes' tags along index in the struct type t let the accessScopes update the field,
+like LoadJSON does. allowed is the permission of the value containing the struct.
+*/
+func FieldAllowed(t reflect.Type, index []int, allowed bool, context string, accessScopes ...string) bool {
+	p := &patcher{
+		context:      context,
+		accessScopes: accessScopes,
 	}
-	return
+	return p.fieldAllowed(t, &field{index: index}, allowed)
 }
@@ -0,0 +1,108 @@
+package json
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type LoadMeta struct {
+	Id    string `json:"id"`
+	Label string `json:"label" PUT_scopes:"user"`
+}
+
+type loadInner struct {
+	A      string `json:"a"`
+	Secret string `json:"secret" PUT_scopes:"admin"`
+}
+
+type loadOuter struct {
+	LoadMeta
+	Name  string               `json:"name" PUT_scopes:"user"`
+	Inner loadInner            `json:"inner" PUT_scopes:"user"`
+	List  []loadInner          `json:"list" PUT_scopes:"user"`
+	Map   map[string]loadInner `json:"map" PUT_scopes:"user"`
+	Ptr   *loadInner           `json:"ptr" PUT_scopes:"user"`
+}
+
+func newLoadOuter() *loadOuter {
+	return &loadOuter{
+		LoadMeta: LoadMeta{Id: "id", Label: "label"},
+		Name:     "name",
+		Inner:    loadInner{A: "a", Secret: "secret"},
+		List:     []loadInner{{A: "a", Secret: "secret"}},
+		Map:      map[string]loadInner{"x": {A: "a", Secret: "secret"}},
+		Ptr:      &loadInner{A: "a"},
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	o := newLoadOuter()
+	in := `{"id":"id","label":"new","name":"new","inner":{"a":"b","secret":"secret"},"map":{"x":{"a":"b"},"y":{"a":"c"}}}`
+	if err := LoadJSON(bytes.NewBufferString(in), o, "PUT", "user"); err != nil {
+		t.Fatal(err)
+	}
+	want := newLoadOuter()
+	want.Label = "new"
+	want.Name = "new"
+	want.Inner.A = "b"
+	want.Map = map[string]loadInner{"x": {A: "b", Secret: "secret"}, "y": {A: "c"}}
+	if !reflect.DeepEqual(o, want) {
+		t.Fatalf("Wanted %+v, got %+v", want, o)
+	}
+
+	in = `{"id":"other","name":"newer","inner":{"secret":"other"},"list":[{"a":"b","secret":"other"}],"map":{"x":{"secret":"other"}}}`
+	err := LoadJSON(bytes.NewBufferString(in), o, "PUT", "user")
+	scopeErr, ok := err.(*ScopeError)
+	if !ok {
+		t.Fatalf("Wanted a *ScopeError, got %v", err)
+	}
+	if wantFields := []string{"/id", "/inner/secret", "/list/0/secret", "/map/x/secret"}; !reflect.DeepEqual(scopeErr.Fields, wantFields) {
+		t.Fatalf("Wanted %v to be rejected, got %v", wantFields, scopeErr.Fields)
+	}
+	if o.Id != "id" || o.Inner.Secret != "secret" || o.Map["x"].Secret != "secret" {
+		t.Fatalf("Rejected fields should not be loaded, got %+v", o)
+	}
+	if o.Name != "newer" {
+		t.Fatalf("Allowed fields should be loaded, got %+v", o)
+	}
+
+	if err := LoadJSON(bytes.NewBufferString(`{"inner":{"secret":"other"}}`), o, "PUT", "user", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if o.Inner.Secret != "other" {
+		t.Fatalf("Wanted the secret to be loaded, got %+v", o.Inner)
+	}
+
+	// null fields, in or out of scope, and null documents, are ignored
+	want = newLoadOuter()
+	for _, in := range []string{`null`, `{"id":null,"name":null,"ptr":null,"inner":{"a":null,"secret":null}}`} {
+		o = newLoadOuter()
+		if err := LoadJSON(bytes.NewBufferString(in), o, "PUT", "user"); err != nil {
+			t.Fatalf("%v: %v", in, err)
+		}
+		if !reflect.DeepEqual(o, want) {
+			t.Errorf("%v: wanted %+v, got %+v", in, want, o)
+		}
+	}
+	if err := LoadJSON(bytes.NewBufferString(`[]`), o, "PUT", "user"); err == nil {
+		t.Errorf("Wanted an error for a document that isn't an object")
+	}
+}
+
+func TestCopyJSON(t *testing.T) {
+	in := newLoadOuter()
+	in.Name = "copied"
+	in.Inner.Secret = "copied"
+	out := newLoadOuter()
+	if err := CopyJSON(in, out, "PUT", "user"); err == nil {
+		t.Fatalf("Copying a changed secret should fail")
+	}
+	if out.Name != "copied" || out.Inner.Secret != "secret" {
+		t.Fatalf("Wrong copy, got %+v", out)
+	}
+	in.Inner.Secret = "secret"
+	if err := CopyJSON(in, out, "PUT", "user"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,540 @@
+/*
+jsongen generates MarshalJSON and UnmarshalJSON methods for struct types, encoding and decoding them the same way the reflective
+Marshal and Unmarshal of github.com/soundtrackyourbrand/utils/json do, but without reflection for fields of basic types.
+
+Run it with go generate, in the directory of the package declaring the types:
+
+	//go:generate jsongen -type=Account,Location
+
+The generated methods honour the 'omitempty' and 'string' options and the jsonTo directive, and pass their args on to the
+Marshalers and Unmarshalers of the fields. Fields that are not strings, booleans or numbers are encoded and decoded using the
+reflective code of the json package. Fields with version tags, see json.FieldVersions, are not supported.
+
+Pointers to the generated types also implement json.GeneratedMarshaler and json.GeneratedUnmarshaler, which let Marshal and
+Unmarshal run the generated code straight on their buffers, and make LoadJSON, ApplyPatch and ApplyMergePatch keep loading
+them field by field to enforce the '<context>_scopes' tags.
+
+Embedded structs are inlined like Marshal does, as long as they are declared in the same package.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const jsonPackage = "github.com/soundtrackyourbrand/utils/json"
+
+var (
+	typeNames = flag.String("type", "", "comma separated list of type names, required")
+	output    = flag.String("output", "", "output file name, default <first type>_jsongen.go")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("jsongen: ")
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	outputName := *output
+	if outputName == "" {
+		outputName = strings.ToLower(types[0]) + "_jsongen.go"
+	}
+	outputName = filepath.Join(dir, outputName)
+
+	g, err := newGenerator(dir, outputName, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := g.generate(types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile(outputName, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generator keeps the parsed package the types are declared in.
+type generator struct {
+	pkgName string
+	structs map[string]*ast.StructType
+	// internal is true when generating code for the json package itself.
+	internal bool
+	buf      bytes.Buffer
+}
+
+func newGenerator(dir, outputName string, types []string) (result *generator, err error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return fi.Name() != filepath.Base(outputName)
+	}, 0)
+	if err != nil {
+		return
+	}
+	for name, pkg := range pkgs {
+		structs := map[string]*ast.StructType{}
+		internal := false
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					if typeSpec.Name.Name == "GeneratedEncoder" && name == "json" {
+						internal = true
+					}
+					if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+						structs[typeSpec.Name.Name] = structType
+					}
+				}
+			}
+		}
+		if structs[types[0]] != nil {
+			result = &generator{
+				pkgName:  name,
+				structs:  structs,
+				internal: internal,
+			}
+			return
+		}
+	}
+	err = fmt.Errorf("no struct type %v found in %v", types[0], dir)
+	return
+}
+
+func (self *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&self.buf, format, args...)
+}
+
+// qualified returns name qualified with the json package if necessary.
+func (self *generator) qualified(name string) string {
+	if self.internal {
+		return name
+	}
+	return "json." + name
+}
+
+func (self *generator) generate(types []string) (result []byte, err error) {
+	self.printf("// Code generated by \"jsongen -type=%v\"; DO NOT EDIT.\n\n", strings.Join(types, ","))
+	self.printf("package %v\n\n", self.pkgName)
+	if !self.internal {
+		self.printf("import %q\n\n", jsonPackage)
+	}
+	for _, typeName := range types {
+		fields, err := self.fields(typeName)
+		if err != nil {
+			return nil, err
+		}
+		self.generateType(typeName, fields)
+	}
+	if result, err = format.Source(self.buf.Bytes()); err != nil {
+		err = fmt.Errorf("generated invalid code: %v\n%s", err, self.buf.Bytes())
+	}
+	return
+}
+
+// step is a step in the path of selectors to a field.
+type step struct {
+	name     string
+	typeName string
+	ptr      bool
+}
+
+// field is a field to encode, found the same way typeFields in the json package finds them.
+type field struct {
+	name      string
+	tagged    bool
+	index     []int
+	path      []step
+	typ       ast.Expr
+	omitEmpty bool
+	quoted    bool
+	to        string
+}
+
+// selector returns the Go selector of the field from the receiver.
+func (self field) selector() string {
+	parts := []string{"self"}
+	for _, s := range self.path {
+		parts = append(parts, s.name)
+	}
+	return strings.Join(parts, ".")
+}
+
+// embedded is an embedded struct to inline.
+type embedded struct {
+	typeName string
+	index    []int
+	path     []step
+}
+
+func fieldName(f *ast.Field) string {
+	if len(f.Names) > 0 {
+		return f.Names[0].Name
+	}
+	t := f.Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	switch t := t.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+// fields returns the fields to encode for typeName, using the same breadth first search over the embedded structs as typeFields in the json package.
+func (self *generator) fields(typeName string) (result []field, err error) {
+	current := []embedded{}
+	next := []embedded{{typeName: typeName}}
+	count := map[string]int{}
+	nextCount := map[string]int{}
+	visited := map[string]bool{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[string]int{}
+
+		for _, e := range current {
+			if visited[e.typeName] {
+				continue
+			}
+			visited[e.typeName] = true
+			structType := self.structs[e.typeName]
+			if structType == nil {
+				return nil, fmt.Errorf("no struct type %v found", e.typeName)
+			}
+
+			i := -1
+			for _, astField := range structType.Fields.List {
+				names := len(astField.Names)
+				if names == 0 {
+					names = 1
+				}
+				for n := 0; n < names; n++ {
+					i++
+					goName := fieldName(astField)
+					if len(astField.Names) > 0 {
+						goName = astField.Names[n].Name
+					}
+					if goName == "" || !ast.IsExported(goName) {
+						continue
+					}
+					tag := reflect.StructTag("")
+					if astField.Tag != nil {
+						unquoted, err := strconv.Unquote(astField.Tag.Value)
+						if err != nil {
+							return nil, err
+						}
+						tag = reflect.StructTag(unquoted)
+					}
+					jsonTag := tag.Get("json")
+					if jsonTag == "-" {
+						continue
+					}
+					for _, versionTag := range []string{"jsonSince", "jsonUntil", "jsonRenamed"} {
+						if tag.Get(versionTag) != "" {
+							return nil, fmt.Errorf("%v.%v has a %v tag, and jsongen can't generate methods for versioned fields", e.typeName, goName, versionTag)
+						}
+					}
+					name, opts := jsonTag, ""
+					if idx := strings.Index(jsonTag, ","); idx != -1 {
+						name, opts = jsonTag[:idx], jsonTag[idx:]
+					}
+					if !isValidTag(name) {
+						name = ""
+					}
+					index := append(append([]int{}, e.index...), i)
+
+					ft := astField.Type
+					ptr := false
+					if star, ok := ft.(*ast.StarExpr); ok {
+						ft = star.X
+						ptr = true
+					}
+					embeddedName := ""
+					if ident, ok := ft.(*ast.Ident); ok && self.structs[ident.Name] != nil {
+						embeddedName = ident.Name
+					}
+					anonymous := len(astField.Names) == 0
+					path := append(append([]step{}, e.path...), step{name: goName, typeName: embeddedName, ptr: ptr})
+
+					if name != "" || !anonymous || embeddedName == "" {
+						if anonymous && name == "" {
+							if _, ok := ft.(*ast.SelectorExpr); ok {
+								return nil, fmt.Errorf("%v embeds %v from another package, which jsongen can't inline", e.typeName, goName)
+							}
+						}
+						tagged := name != ""
+						if name == "" {
+							name = goName
+						}
+						result = append(result, field{
+							name:      name,
+							tagged:    tagged,
+							index:     index,
+							path:      path,
+							typ:       astField.Type,
+							omitEmpty: strings.Contains(opts+",", ",omitempty,"),
+							quoted:    strings.Contains(opts+",", ",string,"),
+							to:        tag.Get("jsonTo"),
+						})
+						if count[e.typeName] > 1 {
+							result = append(result, result[len(result)-1])
+						}
+						continue
+					}
+
+					nextCount[embeddedName]++
+					if nextCount[embeddedName] == 1 {
+						next = append(next, embedded{typeName: embeddedName, index: index, path: path})
+					}
+				}
+			}
+		}
+	}
+
+	sort.Sort(byName(result))
+	out := result[:0]
+	for advance, i := 0, 0; i < len(result); i += advance {
+		name := result[i].name
+		for advance = 1; i+advance < len(result); advance++ {
+			if result[i+advance].name != name {
+				break
+			}
+		}
+		if advance == 1 {
+			out = append(out, result[i])
+			continue
+		}
+		if dominant, ok := dominantField(result[i : i+advance]); ok {
+			out = append(out, dominant)
+		}
+	}
+	result = out
+	sort.Sort(byIndex(result))
+	return
+}
+
+// dominantField works like dominantField in the json package.
+func dominantField(fields []field) (field, bool) {
+	length := len(fields[0].index)
+	tagged := -1
+	for i, f := range fields {
+		if len(f.index) > length {
+			fields = fields[:i]
+			break
+		}
+		if f.tagged {
+			if tagged >= 0 {
+				return field{}, false
+			}
+			tagged = i
+		}
+	}
+	if tagged >= 0 {
+		return fields[tagged], true
+	}
+	if len(fields) > 1 {
+		return field{}, false
+	}
+	return fields[0], true
+}
+
+type byName []field
+
+func (x byName) Len() int { return len(x) }
+
+func (x byName) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+
+func (x byName) Less(i, j int) bool {
+	if x[i].name != x[j].name {
+		return x[i].name < x[j].name
+	}
+	if len(x[i].index) != len(x[j].index) {
+		return len(x[i].index) < len(x[j].index)
+	}
+	if x[i].tagged != x[j].tagged {
+		return x[i].tagged
+	}
+	return byIndex(x).Less(i, j)
+}
+
+type byIndex []field
+
+func (x byIndex) Len() int { return len(x) }
+
+func (x byIndex) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+
+func (x byIndex) Less(i, j int) bool {
+	for k, xik := range x[i].index {
+		if k >= len(x[j].index) {
+			return false
+		}
+		if xik != x[j].index[k] {
+			return xik < x[j].index[k]
+		}
+	}
+	return len(x[i].index) < len(x[j].index)
+}
+
+// isValidTag works like isValidTag in the json package.
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// kind returns the basic kind of the type of f, or "" if it has to be encoded reflectively.
+func (self field) kind() string {
+	if self.to != "" {
+		return ""
+	}
+	ident, ok := self.typ.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	switch ident.Name {
+	case "string", "bool":
+		return ident.Name
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return "int"
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		return "uint"
+	case "float32", "float64":
+		return ident.Name
+	}
+	return ""
+}
+
+// nilChecks returns the conditions for the embedded pointers on the way to f to be non nil.
+func (self field) nilChecks() (result []string) {
+	parts := []string{"self"}
+	for _, s := range self.path[:len(self.path)-1] {
+		parts = append(parts, s.name)
+		if s.ptr {
+			result = append(result, strings.Join(parts, ".")+" != nil")
+		}
+	}
+	return
+}
+
+func upperFirst(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func (self *generator) generateType(typeName string, fields []field) {
+	keys := "jsonKeys" + upperFirst(typeName)
+	self.printf("var %v = []string{\n", keys)
+	for _, f := range fields {
+		self.printf("%q,\n", f.name)
+	}
+	self.printf("}\n\n")
+
+	self.printf("func (self %v) MarshalJSON(args ...interface{}) ([]byte, error) {\n", typeName)
+	self.printf("return %v(&self, args...)\n", self.qualified("MarshalGenerated"))
+	self.printf("}\n\n")
+
+	self.printf("func (self *%v) EncodeJSON(enc *%v) {\n", typeName, self.qualified("GeneratedEncoder"))
+	self.printf("enc.Begin()\n")
+	for _, f := range fields {
+		sel := f.selector()
+		conds := f.nilChecks()
+		if f.omitEmpty {
+			switch f.kind() {
+			case "string":
+				conds = append(conds, sel+` != ""`)
+			case "bool":
+				conds = append(conds, sel)
+			case "int", "uint", "float32", "float64":
+				conds = append(conds, sel+" != 0")
+			default:
+				conds = append(conds, fmt.Sprintf("!%v(&%v)", self.qualified("IsEmpty"), sel))
+			}
+		}
+		if len(conds) > 0 {
+			self.printf("if %v {\n", strings.Join(conds, " && "))
+		}
+		self.printf("enc.Key(%q)\n", f.name)
+		switch f.kind() {
+		case "string":
+			self.printf("enc.String(%v, %v)\n", sel, f.quoted)
+		case "bool":
+			self.printf("enc.Bool(%v, %v)\n", sel, f.quoted)
+		case "int":
+			self.printf("enc.Int(int64(%v), %v)\n", sel, f.quoted)
+		case "uint":
+			self.printf("enc.Uint(uint64(%v), %v)\n", sel, f.quoted)
+		case "float32":
+			self.printf("enc.Float(float64(%v), 32, %v)\n", sel, f.quoted)
+		case "float64":
+			self.printf("enc.Float(%v, 64, %v)\n", sel, f.quoted)
+		default:
+			self.printf("enc.Value(&%v, %v, %q)\n", sel, f.quoted, f.to)
+		}
+		if len(conds) > 0 {
+			self.printf("}\n")
+		}
+	}
+	self.printf("enc.End()\n")
+	self.printf("}\n\n")
+
+	self.printf("func (self *%v) UnmarshalJSON(data []byte, args ...interface{}) error {\n", typeName)
+	self.printf("return %v(data, self, args...)\n", self.qualified("UnmarshalGenerated"))
+	self.printf("}\n\n")
+
+	self.printf("func (self *%v) DecodeJSON(dec *%v) {\n", typeName, self.qualified("GeneratedDecoder"))
+	self.printf("for dec.Next() {\n")
+	self.printf("switch dec.Match(%v) {\n", keys)
+	for index, f := range fields {
+		self.printf("case %v:\n", index)
+		parts := []string{"self"}
+		for _, s := range f.path[:len(f.path)-1] {
+			parts = append(parts, s.name)
+			if s.ptr {
+				sel := strings.Join(parts, ".")
+				self.printf("if %v == nil {\n%v = new(%v)\n}\n", sel, sel, s.typeName)
+			}
+		}
+		self.printf("dec.Value(&%v, %v, %q)\n", f.selector(), f.quoted, f.to)
+	}
+	self.printf("default:\n")
+	self.printf("dec.Skip()\n")
+	self.printf("}\n")
+	self.printf("}\n")
+	self.printf("}\n\n")
+}
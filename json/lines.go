@@ -0,0 +1,149 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+/*
+LineFormat is a format for streams of JSON values, one per line.
+*/
+type LineFormat int
+
+const (
+	// NDJSON is newline delimited JSON, where each value is on its own line.
+	NDJSON LineFormat = iota
+	// JSONSeq is an RFC 7464 JSON text sequence, where each value is preceded by a record separator and followed by a newline.
+	JSONSeq
+)
+
+const recordSeparator = 0x1E
+
+/*
+LineError is returned by LineReader.Decode when a line can't be decoded.
+*/
+type LineError struct {
+	// Line is the number of the line, or of the record in a JSONSeq, counting from 1.
+	Line int
+	Err  error
+}
+
+func (self *LineError) Error() string {
+	return fmt.Sprintf("json: line %v: %v", self.Line, self.Err)
+}
+
+/*
+LineWriter writes JSON values as lines of a stream.
+*/
+type LineWriter struct {
+	w      io.Writer
+	format LineFormat
+	err    error
+}
+
+// NewLineWriter returns a LineWriter writing to w in format.
+func NewLineWriter(w io.Writer, format LineFormat) *LineWriter {
+	return &LineWriter{
+		w:      w,
+		format: format,
+	}
+}
+
+/*
+Encode writes v, encoded with args like Marshal does, as the next line.
+
+If v can't be encoded, nothing is written and the writer may still be used. Errors writing to the underlying writer are
+returned by all later calls.
+*/
+func (self *LineWriter) Encode(v interface{}, args ...interface{}) error {
+	if self.err != nil {
+		return self.err
+	}
+	e := newEncodeState()
+	defer putEncodeState(e)
+	if self.format == JSONSeq {
+		e.WriteByte(recordSeparator)
+	}
+	e.setArgs(args)
+	if err := e.marshal(v); err != nil {
+		return err
+	}
+	e.WriteByte('\n')
+	if _, err := self.w.Write(e.Bytes()); err != nil {
+		self.err = err
+	}
+	return self.err
+}
+
+/*
+LineReader reads JSON values from the lines of a stream.
+*/
+type LineReader struct {
+	r       *bufio.Reader
+	format  LineFormat
+	line    int
+	started bool
+	err     error
+}
+
+// NewLineReader returns a LineReader reading from r in format.
+func NewLineReader(r io.Reader, format LineFormat) *LineReader {
+	return &LineReader{
+		r:      bufio.NewReader(r),
+		format: format,
+	}
+}
+
+/*
+Decode decodes the next line into v with args, like Unmarshal does, skipping empty lines.
+
+It returns io.EOF at the end of the stream. If the line can't be decoded a *LineError is returned, and the reader moves on to
+the next line, so that callers may skip bad lines by calling Decode again. Errors reading from the underlying reader are
+returned by all later calls.
+*/
+func (self *LineReader) Decode(v interface{}, args ...interface{}) error {
+	for {
+		if self.err != nil {
+			return self.err
+		}
+		line, err := self.readLine()
+		if err != nil {
+			self.err = err
+			if err != io.EOF || len(line) == 0 {
+				return err
+			}
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if err := Unmarshal(line, v, args...); err != nil {
+			return &LineError{
+				Line: self.line,
+				Err:  err,
+			}
+		}
+		return nil
+	}
+}
+
+// readLine returns the next line or record, without surrounding white space.
+func (self *LineReader) readLine() (line []byte, err error) {
+	delim := byte('\n')
+	if self.format == JSONSeq {
+		delim = recordSeparator
+	}
+	for {
+		line, err = self.r.ReadBytes(delim)
+		line = bytes.TrimSpace(bytes.TrimSuffix(line, []byte{delim}))
+		started := self.started
+		self.started = true
+		// The empty prefix before the first record separator is not a record.
+		if self.format == JSONSeq && !started && err == nil && len(line) == 0 {
+			continue
+		}
+		self.line++
+		return
+	}
+}
@@ -0,0 +1,75 @@
+package json
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type lineValue struct {
+	A    int     `json:"a"`
+	Echo argEcho `json:"echo"`
+}
+
+func TestLineWriter(t *testing.T) {
+	for format, want := range map[LineFormat]string{
+		NDJSON:  "{\"a\":1,\"echo\":\"x\"}\n{\"a\":2,\"echo\":\"x\"}\n",
+		JSONSeq: "\x1e{\"a\":1,\"echo\":\"x\"}\n\x1e{\"a\":2,\"echo\":\"x\"}\n",
+	} {
+		buf := &bytes.Buffer{}
+		w := NewLineWriter(buf, format)
+		if err := w.Encode(lineValue{A: 1}, "x"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Encode(math.NaN()); err == nil {
+			t.Errorf("Wanted an error for NaN")
+		}
+		if err := w.Encode(lineValue{A: 2}, "x"); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("Wanted %q, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	for format, in := range map[LineFormat]string{
+		NDJSON:  "{\"a\":1,\"echo\":0}\r\n\n{\"a\":\"x\"}\n  {\"a\":3,\"echo\":0}",
+		JSONSeq: "\x1e{\"a\":1,\"echo\":0}\n\x1e\x1e{\"a\":\"x\"}\n\x1e{\"a\"\n:3,\"echo\":0}\n",
+	} {
+		r := NewLineReader(strings.NewReader(in), format)
+		var got []int
+		var lineErrs []int
+		for {
+			var v lineValue
+			err := r.Decode(&v, "y")
+			if err == io.EOF {
+				break
+			}
+			if lineErr, ok := err.(*LineError); ok {
+				lineErrs = append(lineErrs, lineErr.Line)
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Echo != "y" {
+				t.Errorf("Wanted args to be passed on, got %#v", v.Echo)
+			}
+			got = append(got, v.A)
+		}
+		if want := []int{1, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: wanted %v, got %v", format, want, got)
+		}
+		if want := []int{3}; !reflect.DeepEqual(lineErrs, want) {
+			t.Errorf("%v: wanted errors on lines %v, got %v", format, want, lineErrs)
+		}
+		if err := r.Decode(&lineValue{}); err != io.EOF {
+			t.Errorf("Wanted io.EOF to be sticky, got %v", err)
+		}
+	}
+}
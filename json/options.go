@@ -0,0 +1,65 @@
+package json
+
+/*
+Options configure encoding and decoding.
+
+Pass them among the args of Marshal, Unmarshal, Encoder.Encode, Decoder.Decode and the other functions taking args, and they
+will travel through the whole value, and be passed on with the other args to every Marshaler and Unmarshaler, which can
+find them with OptionsFrom.
+*/
+type Options struct {
+	// Context names what the JSON is for, like "bigquery", or "respond" for responses to API requests.
+	Context string
+	// Scopes are the access scopes of whoever the JSON is for.
+	Scopes []string
+	// APIVersion is the version of the API the JSON is for. If set, struct fields are only encoded and decoded in the API
+	// versions their version tags include, under their names in that version, see FieldVersions.
+	APIVersion int
+	// TimeFormat, if set, is the layout that time.Time values are encoded and decoded with, instead of RFC 3339.
+	TimeFormat string
+	// Locale is the locale, like "sv_SE", of whoever the JSON is for.
+	Locale string
+	// Canonical makes the encoding canonical, like passing Canonical does.
+	Canonical bool
+}
+
+/*
+OptionsFrom returns the Options among args.
+
+To let types that handle both typed and untyped args keep working with callers passing untyped args, if there are no
+Options, the first string among args is returned as the Context. Canonical among args always sets Canonical.
+*/
+func OptionsFrom(args ...interface{}) (result Options) {
+	found := false
+	canonical := false
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case Options:
+			if !found {
+				result, found = arg, true
+			}
+		case *Options:
+			if !found && arg != nil {
+				result, found = *arg, true
+			}
+		case canonicalArg:
+			canonical = true
+		case string:
+			if !found && result.Context == "" {
+				result.Context = arg
+			}
+		}
+	}
+	result.Canonical = result.Canonical || canonical
+	return
+}
+
+func (e *encodeState) setArgs(args []interface{}) {
+	e.args = args
+	e.opts = OptionsFrom(args...)
+}
+
+func (d *decodeState) setArgs(args []interface{}) {
+	d.args = args
+	d.opts = OptionsFrom(args...)
+}
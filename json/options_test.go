@@ -0,0 +1,213 @@
+package json
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// optionsEcho encodes as the context of the options it is marshalled with, and decodes into the locale of the options it is unmarshalled with.
+type optionsEcho string
+
+func (self optionsEcho) MarshalJSON(args ...interface{}) ([]byte, error) {
+	return Marshal(OptionsFrom(args...).Context)
+}
+
+func (self *optionsEcho) UnmarshalJSON(b []byte, args ...interface{}) error {
+	*self = optionsEcho(OptionsFrom(args...).Locale)
+	return nil
+}
+
+type optionsValue struct {
+	Echo  []optionsEcho `json:"echo"`
+	When  time.Time     `json:"when"`
+	Other *time.Time    `json:"other"`
+}
+
+func TestOptionsFrom(t *testing.T) {
+	opts := Options{Context: "respond", APIVersion: 2}
+	for _, c := range []struct {
+		args []interface{}
+		want Options
+	}{
+		{nil, Options{}},
+		{[]interface{}{"bigquery"}, Options{Context: "bigquery"}},
+		{[]interface{}{1, "bigquery", "other"}, Options{Context: "bigquery"}},
+		{[]interface{}{"bigquery", opts}, opts},
+		{[]interface{}{&opts, Canonical}, Options{Context: "respond", APIVersion: 2, Canonical: true}},
+	} {
+		if got := OptionsFrom(c.args...); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: wanted %+v, got %+v", c.args, c.want, got)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	when := time.Date(2014, 5, 6, 7, 8, 9, 0, time.UTC)
+	v := optionsValue{Echo: []optionsEcho{""}, When: when, Other: &when}
+	opts := Options{Context: "ctx", TimeFormat: "20060102150405", Locale: "sv_SE", Canonical: true}
+	b, err := Marshal(v, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"echo":["ctx"],"other":"20140506070809","when":"20140506070809"}`; string(b) != want {
+		t.Errorf("Wanted %v, got %s", want, b)
+	}
+
+	var decoded optionsValue
+	if err := Unmarshal(b, &decoded, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.When.Equal(when) || !decoded.Other.Equal(when) || decoded.Echo[0] != "sv_SE" {
+		t.Errorf("Wanted %+v, got %+v", v, decoded)
+	}
+	if err := Unmarshal([]byte(`{"when":"2014-05-06"}`), &decoded, opts); err == nil {
+		t.Errorf("Wanted an error for a time in the wrong format")
+	}
+
+	// Without a TimeFormat, times are encoded as usual.
+	if b, err = Marshal(v, "ctx"); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"echo":["ctx"],"when":"2014-05-06T07:08:09Z","other":"2014-05-06T07:08:09Z"}`; string(b) != want {
+		t.Errorf("Wanted %v, got %s", want, b)
+	}
+}
+
+type versionedSettings struct {
+	Separation int `json:"separation"`
+}
+
+type versionedValue struct {
+	Name       string            `json:"name" jsonRenamed:"3:title,5:label" PUT_scopes:"user"`
+	Separation int               `json:"track_separation,omitempty" jsonUntil:"2" PUT_scopes:"user"`
+	Settings   versionedSettings `json:"settings" jsonSince:"3" PUT_scopes:"user"`
+	Secret     string            `json:"secret" jsonSince:"4"`
+}
+
+func TestOptionsAPIVersion(t *testing.T) {
+	v := versionedValue{Name: "n", Separation: 2, Settings: versionedSettings{Separation: 2}, Secret: "s"}
+	for version, want := range map[int]string{
+		0: `{"name":"n","track_separation":2,"settings":{"separation":2},"secret":"s"}`,
+		1: `{"name":"n","track_separation":2}`,
+		3: `{"title":"n","settings":{"separation":2}}`,
+		6: `{"label":"n","settings":{"separation":2},"secret":"s"}`,
+	} {
+		b, err := Marshal(v, Options{APIVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%v: wanted %v, got %s", version, want, b)
+		}
+	}
+
+	var decoded versionedValue
+	if err := Unmarshal([]byte(`{"name":"a","title":"b","track_separation":1,"settings":{"separation":3}}`), &decoded, Options{APIVersion: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (versionedValue{Name: "b", Settings: versionedSettings{Separation: 3}}); decoded != want {
+		t.Errorf("Wanted %+v, got %+v", want, decoded)
+	}
+
+	loaded := versionedValue{Name: "n", Secret: "s"}
+	if err := LoadJSONOptions(bytes.NewBufferString(`{"LABEL":"x","track_separation":1,"secret":"t"}`), &loaded, Options{Context: "PUT", Scopes: []string{"user"}, APIVersion: 5}); err == nil {
+		t.Errorf("Wanted a scope error for the secret")
+	} else if scopeErr, ok := err.(*ScopeError); !ok || !reflect.DeepEqual(scopeErr.Fields, []string{"/secret"}) {
+		t.Errorf("Wanted a scope error for /secret, got %v", err)
+	}
+	if want := (versionedValue{Name: "x", Secret: "s"}); loaded != want {
+		t.Errorf("Wanted %+v, got %+v", want, loaded)
+	}
+
+	patched := versionedValue{Name: "n", Separation: 2}
+	if err := ApplyMergePatchOptions([]byte(`{"title":"t","track_separation":1}`), &patched, Options{Context: "PUT", Scopes: []string{"user"}, APIVersion: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyPatchOptions([]byte(`[{"op":"replace","path":"/settings/separation","value":4}]`), &patched, Options{Context: "PUT", Scopes: []string{"user"}, APIVersion: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (versionedValue{Name: "t", Separation: 2, Settings: versionedSettings{Separation: 4}}); patched != want {
+		t.Errorf("Wanted %+v, got %+v", want, patched)
+	}
+
+	if want := []int{3, 4, 5}; !reflect.DeepEqual(APIVersions(reflect.TypeOf([]*versionedValue{})), want) {
+		t.Errorf("Wanted %v, got %v", want, APIVersions(reflect.TypeOf([]*versionedValue{})))
+	}
+}
+
+// versionedReplaced replaces a field with another of the same name in API version 4.
+type versionedReplaced struct {
+	Old int               `json:"settings" jsonUntil:"3"`
+	New versionedSettings `json:"settings" jsonSince:"4"`
+}
+
+func TestOptionsAPIVersionReplaced(t *testing.T) {
+	v := versionedReplaced{Old: 1, New: versionedSettings{Separation: 2}}
+	for version, want := range map[int]string{
+		0: `{}`,
+		3: `{"settings":1}`,
+		4: `{"settings":{"separation":2}}`,
+	} {
+		b, err := Marshal(v, Options{APIVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%v: wanted %v, got %s", version, want, b)
+		}
+		var decoded versionedReplaced
+		if err := Unmarshal(b, &decoded, Options{APIVersion: version}); err != nil {
+			t.Fatal(err)
+		}
+		want := versionedReplaced{}
+		switch version {
+		case 3:
+			want.Old = v.Old
+		case 4:
+			want.New = v.New
+		}
+		if decoded != want {
+			t.Errorf("%v: wanted %+v, got %+v", version, want, decoded)
+		}
+	}
+
+	fields := Fields(reflect.TypeOf(versionedReplaced{}))
+	if len(fields) != 2 {
+		t.Fatalf("Wanted 2 fields, got %+v", fields)
+	}
+	for version, want := range map[int][]bool{0: {false, false}, 3: {true, false}, 4: {false, true}} {
+		for i, f := range fields {
+			if _, ok := f.NameAt(version); ok != want[i] {
+				t.Errorf("%v: wanted %v to be included %v, got %v", version, f.Index, want[i], ok)
+			}
+		}
+	}
+}
+
+type optionsJSONTo struct {
+	When     time.Time       `json:"when" jsonTo:"string"`
+	Settings *versionedValue `json:"settings" jsonTo:"string"`
+}
+
+func TestOptionsJSONTo(t *testing.T) {
+	when := time.Date(2014, 5, 6, 7, 8, 9, 0, time.UTC)
+	v := optionsJSONTo{When: when, Settings: &versionedValue{Name: "n"}}
+	opts := Options{TimeFormat: "20060102150405", APIVersion: 3}
+	b, err := Marshal(v, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"when":"20140506070809","settings":"{\"title\":\"n\",\"settings\":{\"separation\":0}}"}`; string(b) != want {
+		t.Errorf("Wanted %v, got %s", want, b)
+	}
+
+	var decoded optionsJSONTo
+	if err := Unmarshal(b, &decoded, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.When.Equal(when) || decoded.Settings == nil || *decoded.Settings != *v.Settings {
+		t.Errorf("Wanted %+v, got %+v", v, decoded)
+	}
+}
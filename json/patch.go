@@ -0,0 +1,856 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+ApplyMergePatch will apply the RFC 7396 JSON Merge Patch patch to out, which must be a pointer to a struct.
+
+Objects in the patch are merged field by field into nested structs and maps, and null removes map entries or zeroes fields.
+
+A field can only be updated if its '<context>_scopes' tag matches one of the accessScopes or is '*'. Fields without such a tag
+inherit the permission of the field containing them, so top level fields without the tag can't be updated at all.
+
+If the patch touches any field out of scope, out is left untouched and a *ScopeError naming the fields is returned. Patches
+that aren't objects can't be merged into a struct, and cause an error before any scopes are checked.
+*/
+func ApplyMergePatch(patch []byte, out interface{}, context string, accessScopes ...string) (err error) {
+	return ApplyMergePatchOptions(patch, out, Options{
+		Context: context,
+		Scopes:  accessScopes,
+	})
+}
+
+/*
+ApplyMergePatchOptions is like ApplyMergePatch with the Context and Scopes of opts, but also only patches the fields included
+in the APIVersion of opts, under their names in that version.
+*/
+func ApplyMergePatchOptions(patch []byte, out interface{}, opts Options) (err error) {
+	if trimmed := bytes.TrimSpace(patch); len(trimmed) == 0 || trimmed[0] != '{' {
+		return fmt.Errorf("json: merge patch %s is not an object", trimmed)
+	}
+	p, dst, err := newPatcher(out, opts)
+	if err != nil {
+		return
+	}
+	if err = p.merge(dst, patch, false, ""); err != nil {
+		return
+	}
+	return p.commit(out, dst)
+}
+
+/*
+ApplyPatch will apply the RFC 6902 JSON Patch patch to out, which must be a pointer to a struct.
+
+Paths are JSON Pointers using the JSON names of the struct fields, and may traverse structs, maps, slices and interface values.
+
+Every location written to, and every location a value is moved from or tested, must be allowed by the '<context>_scopes' tags
+the same way as for ApplyMergePatch, so that tests can't reveal fields the caller can't update. Values added or replaced have
+their nested fields checked against their own scope tags.
+
+If any operation fails or touches any field out of scope, out is left untouched.
+*/
+func ApplyPatch(patch []byte, out interface{}, context string, accessScopes ...string) (err error) {
+	return ApplyPatchOptions(patch, out, Options{
+		Context: context,
+		Scopes:  accessScopes,
+	})
+}
+
+/*
+ApplyPatchOptions is like ApplyPatch with the Context and Scopes of opts, but paths use the names of the fields in the
+APIVersion of opts, and can only point to the fields included in it.
+*/
+func ApplyPatchOptions(patch []byte, out interface{}, opts Options) (err error) {
+	ops, err := parsePatch(patch)
+	if err != nil {
+		return
+	}
+	p, dst, err := newPatcher(out, opts)
+	if err != nil {
+		return
+	}
+	for index, op := range ops {
+		if err = p.apply(dst, op); err != nil {
+			if _, ok := err.(*ScopeError); !ok {
+				err = fmt.Errorf("json: patch operation %v (%#v %#v): %v", index, op.Op, op.Path, err)
+			}
+			return
+		}
+	}
+	return p.commit(out, dst)
+}
+
+type patchOperation struct {
+	Op   string
+	Path string
+	From string
+	// Value is nil if the operation has no value, and null if the value is null.
+	Value RawMessage
+}
+
+// parsePatch decodes the operations of a JSON Patch, keeping track of whether they have values, since null is a valid value.
+func parsePatch(patch []byte) (result []patchOperation, err error) {
+	var members []map[string]RawMessage
+	if err = Unmarshal(patch, &members); err != nil {
+		return
+	}
+	result = make([]patchOperation, len(members))
+	for index, m := range members {
+		op := &result[index]
+		for name, dst := range map[string]*string{"op": &op.Op, "path": &op.Path, "from": &op.From} {
+			if raw, found := m[name]; found {
+				if err = Unmarshal(raw, dst); err != nil {
+					return
+				}
+			}
+		}
+		if raw, found := m["value"]; found {
+			op.Value = append(RawMessage{}, bytes.TrimSpace(raw)...)
+		}
+	}
+	return
+}
+
+// patcher applies patches to a struct while keeping track of scope violations.
+type patcher struct {
+	context      string
+	accessScopes []string
+	apiVersion   int
+	rejected     []string
+	// replace makes merge replace nested objects instead of merging into them.
+	replace bool
+	// load makes merge ignore null fields and decode other nulls like Unmarshal does, and ignore values out of scope that
+	// wouldn't change anything.
+	load bool
+}
+
+// newPatcher validates that out is a pointer to a struct, and returns a patcher
+// and a deep copy of the struct to patch.
+func newPatcher(out interface{}, opts Options) (p *patcher, dst reflect.Value, err error) {
+	structPointerValue := reflect.ValueOf(out)
+	if structPointerValue.Kind() != reflect.Ptr || structPointerValue.IsNil() || structPointerValue.Elem().Kind() != reflect.Struct {
+		err = fmt.Errorf("%#v is not a pointer to a struct", out)
+		return
+	}
+	p = &patcher{
+		context:      opts.Context,
+		accessScopes: opts.Scopes,
+		apiVersion:   opts.APIVersion,
+	}
+	dst = reflect.New(structPointerValue.Elem().Type()).Elem()
+	dst.Set(deepCopy(structPointerValue.Elem()))
+	return
+}
+
+// commit stores dst in out, unless any fields were rejected.
+func (self *patcher) commit(out interface{}, dst reflect.Value) error {
+	if err := self.scopeError(); err != nil {
+		return err
+	}
+	reflect.ValueOf(out).Elem().Set(dst)
+	return nil
+}
+
+// options returns the Options that values are decoded with.
+func (self *patcher) options() Options {
+	return Options{
+		Context:    self.context,
+		Scopes:     self.accessScopes,
+		APIVersion: self.apiVersion,
+	}
+}
+
+func (self *patcher) scopeError() error {
+	if len(self.rejected) == 0 {
+		return nil
+	}
+	sort.Strings(self.rejected)
+	return &ScopeError{Fields: self.rejected}
+}
+
+// allowed returns whether the scopes tag of sf allows an update, or inherited if it has none.
+func (self *patcher) allowed(sf reflect.StructField, inherited bool) bool {
+	updateScopesTag := sf.Tag.Get(self.context + "_scopes")
+	if updateScopesTag == "" {
+		return inherited
+	}
+	if updateScopesTag == "*" {
+		return true
+	}
+	for _, allowedScope := range strings.Split(updateScopesTag, ",") {
+		for _, scope := range self.accessScopes {
+			if scope == allowedScope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check records path as rejected unless allowed.
+func (self *patcher) check(allowed bool, path string) bool {
+	if !allowed {
+		self.rejected = append(self.rejected, path)
+	}
+	return allowed
+}
+
+// store calls f with v if allowed. Otherwise path is rejected, unless loading and f wouldn't change the JSON encoding of v.
+func (self *patcher) store(v reflect.Value, allowed bool, path string, f func(v reflect.Value) error) (err error) {
+	if allowed {
+		return f(v)
+	}
+	if self.load {
+		tmp := reflect.New(v.Type()).Elem()
+		tmp.Set(deepCopy(v))
+		if err = f(tmp); err != nil {
+			return
+		}
+		before, beforeErr := Marshal(v.Interface())
+		after, afterErr := Marshal(tmp.Interface())
+		if beforeErr == nil && afterErr == nil && bytes.Equal(before, after) {
+			return
+		}
+	}
+	self.check(false, path)
+	return
+}
+
+// structField finds the field of the struct v with the JSON name key, allocating embedded pointers on the way,
+// and returns it along with its field description.
+func (self *patcher) structField(v reflect.Value, key string) (fv reflect.Value, f *field) {
+	if f = lookupField(cachedTypeFields(v.Type()), []byte(key), self.apiVersion); f == nil {
+		return
+	}
+	fv = v
+	for _, i := range f.index {
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			fv = fv.Elem()
+		}
+		fv = fv.Field(i)
+	}
+	return
+}
+
+// fieldAllowed returns whether the scopes tags along the index of f in t allow updating it.
+func (self *patcher) fieldAllowed(t reflect.Type, f *field, allowed bool) bool {
+	for _, i := range f.index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		sf := t.Field(i)
+		allowed = self.allowed(sf, allowed)
+		t = sf.Type
+	}
+	return allowed
+}
+
+// storeField unmarshals data into the field f of the struct v, honouring the tag options of f.
+func storeField(v reflect.Value, f *field, data []byte) (err error) {
+	if !f.quoted && f.to == "" {
+		return Unmarshal(data, fieldByIndex(v, f.index).Addr().Interface())
+	}
+	enc := &encodeState{}
+	enc.WriteByte('{')
+	enc.string(f.name)
+	enc.WriteByte(':')
+	enc.Write(data)
+	enc.WriteByte('}')
+	tmp := reflect.New(v.Type())
+	if err = Unmarshal(enc.Bytes(), tmp.Interface()); err != nil {
+		return
+	}
+	fieldByIndex(v, f.index).Set(fieldByIndex(tmp.Elem(), f.index))
+	return
+}
+
+// hasUnmarshaler returns whether values of t decode themselves, other than with methods generated by jsongen.
+func hasUnmarshaler(t reflect.Type) bool {
+	if t.Implements(generatedUnmarshalerType) || reflect.PtrTo(t).Implements(generatedUnmarshalerType) {
+		return false
+	}
+	for _, u := range []reflect.Type{unmarshalerType, simpleUnmarshalerType, textUnmarshalerType} {
+		if t.Implements(u) || reflect.PtrTo(t).Implements(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeable returns whether objects decoded into t can be merged field by field.
+func mergeable(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		if hasUnmarshaler(t) {
+			return false
+		}
+		t = t.Elem()
+	}
+	if hasUnmarshaler(t) {
+		return false
+	}
+	return t.Kind() == reflect.Struct || (t.Kind() == reflect.Map && t.Key().Kind() == reflect.String)
+}
+
+/*
+merge will merge data into the addressable v, checking scopes of all fields it touches.
+
+allowed is the permission inherited from the containing field, and path the JSON Pointer to v.
+*/
+func (self *patcher) merge(v reflect.Value, data []byte, allowed bool, path string) (err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("json: empty value at %#v", path)
+	}
+	if data[0] == '[' {
+		if t := indirectType(v.Type()); t.Kind() == reflect.Slice && mergeable(t.Elem()) {
+			return self.mergeSlice(v, data, allowed, path)
+		}
+	}
+	if data[0] != '{' || !mergeable(v.Type()) {
+		return self.store(v, allowed, path, func(v reflect.Value) error {
+			return Unmarshal(data, v.Addr().Interface(), self.options())
+		})
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if self.replace {
+		v.Set(reflect.Zero(v.Type()))
+	}
+	var patch map[string]RawMessage
+	if err = Unmarshal(data, &patch); err != nil {
+		return
+	}
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if v.Kind() == reflect.Map {
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, key := range keys {
+			value := patch[key]
+			keyPath := path + "/" + escapePointer(key)
+			kv := reflect.ValueOf(key).Convert(v.Type().Key())
+			if !self.load && string(bytes.TrimSpace(value)) == "null" {
+				if self.check(allowed, keyPath) {
+					v.SetMapIndex(kv, reflect.Value{})
+				}
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if existing := v.MapIndex(kv); existing.IsValid() {
+				elem.Set(existing)
+			}
+			if err = self.merge(elem, value, allowed, keyPath); err != nil {
+				return
+			}
+			v.SetMapIndex(kv, elem)
+		}
+		return
+	}
+
+	for _, key := range keys {
+		value := patch[key]
+		fv, f := self.structField(v, key)
+		if f == nil {
+			continue
+		}
+		name, _ := f.nameAt(self.apiVersion)
+		fieldPath := path + "/" + escapePointer(name)
+		fieldAllowed := self.fieldAllowed(v.Type(), f, allowed)
+		if string(bytes.TrimSpace(value)) == "null" {
+			if self.load {
+				continue
+			}
+			if self.check(fieldAllowed, fieldPath) {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+			continue
+		}
+		if f.quoted || f.to != "" {
+			if err = self.store(v, fieldAllowed, fieldPath, func(v reflect.Value) error {
+				return storeField(v, f, value)
+			}); err != nil {
+				return
+			}
+			continue
+		}
+		if err = self.merge(fv, value, fieldAllowed, fieldPath); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// mergeSlice replaces the slice in v with the array in data, checking the scopes of the fields of each element.
+func (self *patcher) mergeSlice(v reflect.Value, data []byte, allowed bool, path string) (err error) {
+	if !allowed {
+		return self.store(v, allowed, path, func(v reflect.Value) error {
+			return Unmarshal(data, v.Addr().Interface(), self.options())
+		})
+	}
+	var elems []RawMessage
+	if err = Unmarshal(data, &elems); err != nil {
+		return
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	result := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+	oldReplace := self.replace
+	self.replace = true
+	if self.load {
+		// when loading, elements are decoded into the existing ones like Unmarshal does, so that unchanged values out of scope are accepted
+		self.replace = false
+		for index := 0; index < len(elems) && index < v.Len(); index++ {
+			result.Index(index).Set(deepCopy(v.Index(index)))
+		}
+	}
+	defer func() {
+		self.replace = oldReplace
+	}()
+	for index, elem := range elems {
+		if err = self.merge(result.Index(index), elem, allowed, path+"/"+strconv.Itoa(index)); err != nil {
+			return
+		}
+	}
+	v.Set(result)
+	return
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// replaceValue stores data in v as a new value, with nested fields checked against their own scopes.
+func (self *patcher) replaceValue(v reflect.Value, data []byte, allowed bool, path string) (err error) {
+	if !self.check(allowed, path) {
+		return
+	}
+	fresh := reflect.New(v.Type()).Elem()
+	oldReplace := self.replace
+	self.replace = true
+	defer func() {
+		self.replace = oldReplace
+	}()
+	if err = self.merge(fresh, data, allowed, path); err != nil {
+		return
+	}
+	v.Set(fresh)
+	return
+}
+
+// locate calls f with the container of the location the tokens point to in the addressable v,
+// the last token, and whether the location may be updated.
+func (self *patcher) locate(v reflect.Value, tokens []string, allowed bool, path string, f func(container reflect.Value, token string, allowed bool, path string) error) (err error) {
+	if len(tokens) == 1 {
+		return f(v, tokens[0], allowed, path+"/"+escapePointer(tokens[0]))
+	}
+	token := tokens[0]
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fmt.Errorf("%#v is null", path)
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fv, sf := self.structField(v, token)
+		if sf == nil {
+			return fmt.Errorf("%#v not found in %v", token, v.Type())
+		}
+		name, _ := sf.nameAt(self.apiVersion)
+		return self.locate(fv, tokens[1:], self.fieldAllowed(v.Type(), sf, allowed), path+"/"+escapePointer(name), f)
+	case reflect.Map:
+		kv := reflect.ValueOf(token).Convert(v.Type().Key())
+		existing := v.MapIndex(kv)
+		if !existing.IsValid() {
+			return fmt.Errorf("%#v not found in %v", token, path)
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		elem.Set(existing)
+		if err = self.locate(elem, tokens[1:], allowed, path+"/"+escapePointer(token), f); err != nil {
+			return
+		}
+		v.SetMapIndex(kv, elem)
+		return
+	case reflect.Slice, reflect.Array:
+		index, err := sliceIndex(token, v.Len(), false)
+		if err != nil {
+			return err
+		}
+		return self.locate(v.Index(index), tokens[1:], allowed, path+"/"+token, f)
+	case reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("%#v is null", path)
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err = self.locate(elem, tokens[1:], allowed, path+"/"+escapePointer(token), f); err != nil {
+			return
+		}
+		v.Set(elem)
+		return
+	}
+	return fmt.Errorf("can't traverse %v at %#v", v.Type(), path)
+}
+
+// sliceIndex parses token as an index into a slice of length l. If adding, the index may be l or '-'.
+func sliceIndex(token string, l int, adding bool) (index int, err error) {
+	if adding && token == "-" {
+		return l, nil
+	}
+	if index, err = arrayIndex(token); err != nil {
+		return
+	}
+	if index > l || (!adding && index == l) {
+		return 0, fmt.Errorf("index %v out of bounds", index)
+	}
+	return
+}
+
+// get returns the value of the child token of the container v.
+func (self *patcher) get(v reflect.Value, token string, allowed bool) (result reflect.Value, childAllowed bool, err error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			err = fmt.Errorf("parent of %#v is null", token)
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fv, f := self.structField(v, token)
+		if f == nil {
+			err = fmt.Errorf("%#v not found in %v", token, v.Type())
+			return
+		}
+		return fv, self.fieldAllowed(v.Type(), f, allowed), nil
+	case reflect.Map:
+		result = v.MapIndex(reflect.ValueOf(token).Convert(v.Type().Key()))
+		if !result.IsValid() {
+			err = fmt.Errorf("%#v not found", token)
+		}
+		return result, allowed, err
+	case reflect.Slice, reflect.Array:
+		var index int
+		if index, err = sliceIndex(token, v.Len(), false); err != nil {
+			return
+		}
+		return v.Index(index), allowed, nil
+	}
+	err = fmt.Errorf("%v has no children", v.Type())
+	return
+}
+
+// add adds data as the child token of the container v.
+func (self *patcher) add(v reflect.Value, token string, data []byte, allowed bool, path string) (err error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fmt.Errorf("parent of %#v is null", path)
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fv, f := self.structField(v, token)
+		if f == nil {
+			return fmt.Errorf("%#v not found in %v", token, v.Type())
+		}
+		if f.quoted || f.to != "" {
+			if self.check(self.fieldAllowed(v.Type(), f, allowed), path) {
+				return storeField(v, f, data)
+			}
+			return
+		}
+		return self.replaceValue(fv, data, self.fieldAllowed(v.Type(), f, allowed), path)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err = self.replaceValue(elem, data, allowed, path); err != nil {
+			return
+		}
+		v.SetMapIndex(reflect.ValueOf(token).Convert(v.Type().Key()), elem)
+		return
+	case reflect.Slice:
+		index, err := sliceIndex(token, v.Len(), true)
+		if err != nil {
+			return err
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err = self.replaceValue(elem, data, allowed, path); err != nil {
+			return err
+		}
+		result := reflect.MakeSlice(v.Type(), 0, v.Len()+1)
+		result = reflect.AppendSlice(result, v.Slice(0, index))
+		result = reflect.Append(result, elem)
+		result = reflect.AppendSlice(result, v.Slice(index, v.Len()))
+		v.Set(result)
+		return nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("parent of %#v is null", path)
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err = self.add(elem, token, data, allowed, path); err != nil {
+			return
+		}
+		v.Set(elem)
+		return
+	}
+	return fmt.Errorf("can't add %#v to %v", token, v.Type())
+}
+
+// remove removes the child token from the container v.
+func (self *patcher) remove(v reflect.Value, token string, allowed bool, path string) (err error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fmt.Errorf("parent of %#v is null", path)
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fv, f := self.structField(v, token)
+		if f == nil {
+			return fmt.Errorf("%#v not found in %v", token, v.Type())
+		}
+		if self.check(self.fieldAllowed(v.Type(), f, allowed), path) {
+			fv.Set(reflect.Zero(fv.Type()))
+		}
+		return
+	case reflect.Map:
+		kv := reflect.ValueOf(token).Convert(v.Type().Key())
+		if !v.MapIndex(kv).IsValid() {
+			return fmt.Errorf("%#v not found", path)
+		}
+		if self.check(allowed, path) {
+			v.SetMapIndex(kv, reflect.Value{})
+		}
+		return
+	case reflect.Slice:
+		index, err := sliceIndex(token, v.Len(), false)
+		if err != nil {
+			return err
+		}
+		if self.check(allowed, path) {
+			result := reflect.MakeSlice(v.Type(), 0, v.Len()-1)
+			result = reflect.AppendSlice(result, v.Slice(0, index))
+			result = reflect.AppendSlice(result, v.Slice(index+1, v.Len()))
+			v.Set(result)
+		}
+		return nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("parent of %#v is null", path)
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err = self.remove(elem, token, allowed, path); err != nil {
+			return
+		}
+		v.Set(elem)
+		return
+	}
+	return fmt.Errorf("can't remove %#v from %v", token, v.Type())
+}
+
+// value returns the JSON encoding of the location the pointer path points to in v.
+func (self *patcher) value(v reflect.Value, pointer string) (result []byte, allowed bool, err error) {
+	tokens, err := ParsePointer(pointer)
+	if err != nil {
+		return
+	}
+	if len(tokens) == 0 {
+		result, err = Marshal(v.Interface())
+		return
+	}
+	err = self.locate(v, tokens, false, "", func(container reflect.Value, token string, containerAllowed bool, path string) (err error) {
+		child, childAllowed, err := self.get(container, token, containerAllowed)
+		if err != nil {
+			return
+		}
+		allowed = childAllowed
+		result, err = Marshal(child.Interface())
+		return
+	})
+	return
+}
+
+// apply applies a single JSON Patch operation to v.
+func (self *patcher) apply(v reflect.Value, op patchOperation) (err error) {
+	tokens, err := ParsePointer(op.Path)
+	if err != nil {
+		return
+	}
+	var data []byte
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return fmt.Errorf("missing value")
+		}
+		data = op.Value
+	case "move", "copy":
+		var fromAllowed bool
+		if data, fromAllowed, err = self.value(v, op.From); err != nil {
+			return
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return fmt.Errorf("can't move %#v into itself", op.From)
+			}
+			if !self.check(fromAllowed, op.From) {
+				return self.scopeError()
+			}
+			fromTokens, _ := ParsePointer(op.From)
+			if len(fromTokens) == 0 {
+				return fmt.Errorf("can't move the whole document")
+			}
+			if err = self.locate(v, fromTokens, false, "", func(container reflect.Value, token string, allowed bool, path string) error {
+				return self.remove(container, token, allowed, path)
+			}); err != nil {
+				return
+			}
+		}
+	case "remove":
+	default:
+		return fmt.Errorf("unknown operation %#v", op.Op)
+	}
+
+	if op.Op == "test" {
+		current, allowed, err := self.value(v, op.Path)
+		if err != nil {
+			return err
+		}
+		if !self.check(allowed, op.Path) {
+			return self.scopeError()
+		}
+		var want, got interface{}
+		if err = Unmarshal(data, &want); err != nil {
+			return err
+		}
+		if err = Unmarshal(current, &got); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(want, got) {
+			return fmt.Errorf("%s is not %s", current, data)
+		}
+		return nil
+	}
+
+	if len(tokens) == 0 {
+		if op.Op == "remove" {
+			return fmt.Errorf("can't remove the whole document")
+		}
+		return self.replaceValue(v, data, false, "")
+	}
+	err = self.locate(v, tokens, false, "", func(container reflect.Value, token string, allowed bool, path string) (err error) {
+		switch op.Op {
+		case "remove":
+			return self.remove(container, token, allowed, path)
+		case "replace":
+			if _, _, err = self.get(container, token, allowed); err != nil {
+				return
+			}
+		}
+		return self.add(container, token, data, allowed, path)
+	})
+	if err == nil {
+		err = self.scopeError()
+	}
+	return
+}
+
+// deepCopy returns a copy of v that shares no pointers, maps or slices with it, except in unexported fields.
+func deepCopy(v reflect.Value) (result reflect.Value) {
+	result = reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			result.Set(reflect.New(v.Type().Elem()))
+			result.Elem().Set(deepCopy(v.Elem()))
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			result.Set(deepCopy(v.Elem()))
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			result.Set(reflect.MakeMap(v.Type()))
+			for _, key := range v.MapKeys() {
+				result.SetMapIndex(key, deepCopy(v.MapIndex(key)))
+			}
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			result.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				result.Index(i).Set(deepCopy(v.Index(i)))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(deepCopy(v.Index(i)))
+		}
+	case reflect.Struct:
+		result.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				result.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+	default:
+		result.Set(v)
+	}
+	return
+}
+
+// escapePointer escapes a reference token of a JSON Pointer.
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+/*
+ParsePointer splits the RFC 6901 JSON Pointer pointer into its reference tokens, unescaped. The empty pointer, to the whole
+document, has no tokens.
+*/
+func ParsePointer(pointer string) (tokens []string, err error) {
+	if pointer == "" {
+		return
+	}
+	if pointer[0] != '/' {
+		err = fmt.Errorf("json: invalid JSON Pointer %#v", pointer)
+		return
+	}
+	tokens = strings.Split(pointer[1:], "/")
+	for index, token := range tokens {
+		tokens[index] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return
+}
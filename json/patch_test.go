@@ -0,0 +1,171 @@
+package json
+
+import (
+	"reflect"
+	"testing"
+)
+
+type patchInner struct {
+	A      string `json:"a"`
+	Secret string `json:"secret" PATCH_scopes:"admin"`
+}
+
+type patchOuter struct {
+	Name    string                 `json:"name" PATCH_scopes:"user,admin"`
+	Hidden  string                 `json:"hidden"`
+	Inner   patchInner             `json:"inner" PATCH_scopes:"user"`
+	Ptr     *patchInner            `json:"ptr" PATCH_scopes:"user"`
+	Tags    map[string]string      `json:"tags" PATCH_scopes:"user"`
+	List    []patchInner           `json:"list" PATCH_scopes:"user"`
+	Numbers []int                  `json:"numbers" PATCH_scopes:"user"`
+	Count   int                    `json:"count" jsonTo:"string" PATCH_scopes:"*"`
+	Any     map[string]interface{} `json:"any" PATCH_scopes:"user"`
+}
+
+func newPatchOuter() *patchOuter {
+	return &patchOuter{
+		Name:    "name",
+		Hidden:  "hidden",
+		Inner:   patchInner{A: "a", Secret: "secret"},
+		Tags:    map[string]string{"x": "1", "y": "2"},
+		List:    []patchInner{{A: "first"}},
+		Numbers: []int{1, 2, 3},
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	o := newPatchOuter()
+	if err := ApplyMergePatch([]byte(`{"name":"new","inner":{"a":"b"},"ptr":{"a":"c"},"tags":{"x":null,"z":"3"},"count":"4"}`), o, "PATCH", "user"); err != nil {
+		t.Fatal(err)
+	}
+	want := newPatchOuter()
+	want.Name = "new"
+	want.Inner.A = "b"
+	want.Ptr = &patchInner{A: "c"}
+	want.Tags = map[string]string{"y": "2", "z": "3"}
+	want.Count = 4
+	if !reflect.DeepEqual(o, want) {
+		t.Fatalf("Wanted %+v, got %+v", want, o)
+	}
+
+	err := ApplyMergePatch([]byte(`{"name":"newer","hidden":"x","inner":{"secret":"y"}}`), o, "PATCH", "user")
+	scopeErr, ok := err.(*ScopeError)
+	if !ok {
+		t.Fatalf("Wanted a *ScopeError, got %v", err)
+	}
+	if wantFields := []string{"/hidden", "/inner/secret"}; !reflect.DeepEqual(scopeErr.Fields, wantFields) {
+		t.Fatalf("Wanted %v to be rejected, got %v", wantFields, scopeErr.Fields)
+	}
+	if o.Name != "new" {
+		t.Fatalf("Rejected patch should leave the struct untouched, got %+v", o)
+	}
+
+	if err := ApplyMergePatch([]byte(`{"inner":{"secret":"y"}}`), o, "PATCH", "user", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if o.Inner.Secret != "y" || o.Inner.A != "b" {
+		t.Fatalf("Wrong merge of nested struct: %+v", o.Inner)
+	}
+
+	if err := ApplyMergePatch([]byte(`{"list":[{"secret":"s"}]}`), o, "PATCH", "user"); err == nil {
+		t.Fatalf("Scopes of fields of slice elements should be checked")
+	}
+
+	for _, bad := range []string{`[1]`, ` "x"`, `null`, ``} {
+		err := ApplyMergePatch([]byte(bad), o, "PATCH", "user")
+		if _, isScopeErr := err.(*ScopeError); err == nil || isScopeErr {
+			t.Errorf("Wanted %#v to fail for not being an object, got %v", bad, err)
+		}
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	o := newPatchOuter()
+	patch := `[
+		{"op": "test", "path": "/inner/a", "value": "a"},
+		{"op": "replace", "path": "/inner/a", "value": "b"},
+		{"op": "add", "path": "/numbers/1", "value": 9},
+		{"op": "add", "path": "/numbers/-", "value": 4},
+		{"op": "remove", "path": "/numbers/0"},
+		{"op": "add", "path": "/list/-", "value": {"a": "second"}},
+		{"op": "add", "path": "/tags/z", "value": "3"},
+		{"op": "move", "path": "/tags/w", "from": "/tags/x"},
+		{"op": "copy", "path": "/name", "from": "/inner/a"},
+		{"op": "add", "path": "/any", "value": {"deep": {"er": 1}}},
+		{"op": "add", "path": "/any/deep/est", "value": 2}
+	]`
+	if err := ApplyPatch([]byte(patch), o, "PATCH", "user"); err != nil {
+		t.Fatal(err)
+	}
+	want := newPatchOuter()
+	want.Inner.A = "b"
+	want.Numbers = []int{9, 2, 3, 4}
+	want.List = append(want.List, patchInner{A: "second"})
+	want.Tags = map[string]string{"w": "1", "y": "2", "z": "3"}
+	want.Name = "b"
+	want.Any = map[string]interface{}{"deep": map[string]interface{}{"er": 1.0, "est": 2.0}}
+	if !reflect.DeepEqual(o, want) {
+		t.Fatalf("Wanted %+v, got %+v", want, o)
+	}
+
+	for _, bad := range []string{
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "test", "path": "/name", "value": "y"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "remove", "path": "/numbers/10"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "remove", "path": "/numbers/+1"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "remove", "path": "/numbers/-1"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "remove", "path": "/numbers/01"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "replace", "path": "/hidden", "value": "y"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "move", "path": "/name", "from": "/inner/secret"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "add", "path": "/list/0", "value": {"secret": "s"}}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "jump", "path": "/name"}]`,
+		`[{"op": "replace", "path": "/name", "value": "x"}, {"op": "test", "path": "/inner/secret", "value": "secret"}]`,
+	} {
+		if err := ApplyPatch([]byte(bad), o, "PATCH", "user"); err == nil {
+			t.Errorf("%v should fail", bad)
+		}
+		if o.Name != "b" {
+			t.Fatalf("Failed patch %v should leave the struct untouched, got %+v", bad, o)
+		}
+	}
+
+	nulls := newPatchOuter()
+	nulls.Ptr = &patchInner{A: "p"}
+	nulls.Any = map[string]interface{}{"x": 1.0}
+	patch = `[
+		{"op": "test", "path": "/any/y", "value": null},
+		{"op": "replace", "path": "/ptr", "value": null},
+		{"op": "test", "path": "/ptr", "value": null},
+		{"op": "add", "path": "/any/x", "value": null}
+	]`
+	nulls.Any["y"] = nil
+	if err := ApplyPatch([]byte(patch), nulls, "PATCH", "user"); err != nil {
+		t.Fatal(err)
+	}
+	if nulls.Ptr != nil || !reflect.DeepEqual(nulls.Any, map[string]interface{}{"x": nil, "y": nil}) {
+		t.Errorf("Wanted null values to be added, replaced and tested, got %+v", nulls)
+	}
+	if err := ApplyPatch([]byte(`[{"op": "add", "path": "/name"}]`), nulls, "PATCH", "user"); err == nil {
+		t.Errorf("Wanted an error for an add without a value")
+	}
+
+	err := ApplyPatch([]byte(`[{"op": "test", "path": "/hidden", "value": "hidden"}]`), o, "PATCH", "user")
+	if scopeErr, ok := err.(*ScopeError); !ok || !reflect.DeepEqual(scopeErr.Fields, []string{"/hidden"}) {
+		t.Errorf("Wanted tests of fields out of scope to be rejected, got %v", err)
+	}
+}
+
+func TestPointers(t *testing.T) {
+	tokens, err := ParsePointer("/a~1b/c~0d/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a/b", "c~d", ""}; !reflect.DeepEqual(tokens, want) {
+		t.Fatalf("Wanted %#v, got %#v", want, tokens)
+	}
+	if escapePointer("a/b~c") != "a~1b~0c" {
+		t.Fatalf("Wrong escape of %#v", "a/b~c")
+	}
+	if _, err := ParsePointer("a"); err == nil {
+		t.Fatalf("Pointers must start with /")
+	}
+}
@@ -0,0 +1,467 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Get returns the value that the RFC 6901 JSON Pointer points to in m, and whether there was one.
+
+Only the objects and arrays along the pointer are scanned, up to the members and elements it passes through. Everything else
+is skipped by the scanner without being decoded, so plucking one field out of a large document is cheap. err is only
+returned for invalid pointers, and syntax errors in the scanned parts of m.
+*/
+func (m RawMessage) Get(pointer string) (result RawMessage, found bool, err error) {
+	tokens, err := ParsePointer(pointer)
+	if err != nil {
+		return
+	}
+	data := bytes.TrimSpace(m)
+	for _, token := range tokens {
+		if len(data) == 0 {
+			return
+		}
+		switch data[0] {
+		case '{':
+			var value []byte
+			if err = eachMember(data, func(key string, v []byte) bool {
+				if key == token {
+					value = v
+					return false
+				}
+				return true
+			}); err != nil || value == nil {
+				return
+			}
+			data = value
+		case '[':
+			var index int
+			if index, err = arrayIndex(token); err != nil {
+				return
+			}
+			var value []byte
+			if err = eachElement(data, func(i int, v []byte) bool {
+				if i == index {
+					value = v
+					return false
+				}
+				return true
+			}); err != nil || value == nil {
+				return
+			}
+			data = value
+		default:
+			return
+		}
+	}
+	return RawMessage(data), true, nil
+}
+
+// arrayIndex parses token as an RFC 6901 array index, which has no sign or leading zeros.
+func arrayIndex(token string) (index int, err error) {
+	if index, err = strconv.Atoi(token); err != nil || token[0] < '0' || token[0] > '9' || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%#v is not an array index", token)
+	}
+	return
+}
+
+/*
+GetInto unmarshals the value that the JSON Pointer points to in m into v with args, and returns whether there was one.
+*/
+func (m RawMessage) GetInto(pointer string, v interface{}, args ...interface{}) (found bool, err error) {
+	value, found, err := m.Get(pointer)
+	if err != nil || !found {
+		return
+	}
+	err = Unmarshal(value, v, args...)
+	return
+}
+
+/*
+Query returns the values in m that match the JSONPath expression path, in document order.
+
+Like Get, Query only scans the parts of m it needs to match path. It supports this subset of JSONPath:
+
+	$             the root value
+	.name         the member name of an object
+	['name']      the member name of an object, which may contain any characters ('\'' and '\\' escaped)
+	[2]           the element at index 2 of an array, where negative indexes count from the end
+	[1:3]         the elements from index 1 to, but not including, 3, where both bounds are optional and may be negative
+	.* and [*]    all members of an object, or all elements of an array
+	[0,'a']       the union of the selectors separated by commas
+	..name, ..*,  the selectors applied to the value, and all values it contains, recursively
+	..[...]
+
+Filter and script expressions are not supported.
+*/
+func (m RawMessage) Query(path string) (result []RawMessage, err error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return
+	}
+	values := [][]byte{bytes.TrimSpace(m)}
+	for _, seg := range segments {
+		var next [][]byte
+		for _, value := range values {
+			if next, err = seg.apply(value, next); err != nil {
+				return
+			}
+		}
+		values = next
+	}
+	for _, value := range values {
+		result = append(result, RawMessage(value))
+	}
+	return
+}
+
+type selectorKind int
+
+const (
+	nameSelector selectorKind = iota
+	indexSelector
+	sliceSelector
+	wildcardSelector
+)
+
+type pathSelector struct {
+	kind  selectorKind
+	name  string
+	index int
+	start *int
+	end   *int
+}
+
+type pathSegment struct {
+	descendant bool
+	selectors  []pathSelector
+}
+
+// apply appends the values in value that match the segment to result.
+func (self pathSegment) apply(value []byte, result [][]byte) ([][]byte, error) {
+	result, err := self.selectFrom(value, result)
+	if err != nil || !self.descendant {
+		return result, err
+	}
+	children, err := childValues(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if result, err = self.apply(child, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// selectFrom appends the children of value that match the selectors of the segment to result.
+func (self pathSegment) selectFrom(value []byte, result [][]byte) ([][]byte, error) {
+	if len(value) == 0 || (value[0] != '{' && value[0] != '[') {
+		return result, nil
+	}
+	var elements [][]byte
+	for _, sel := range self.selectors {
+		if sel.kind == wildcardSelector {
+			children, err := childValues(value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, children...)
+			continue
+		}
+		if value[0] == '{' {
+			if sel.kind != nameSelector {
+				continue
+			}
+			if err := eachMember(value, func(key string, v []byte) bool {
+				if key == sel.name {
+					result = append(result, v)
+					return false
+				}
+				return true
+			}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if sel.kind == nameSelector {
+			continue
+		}
+		if elements == nil {
+			var err error
+			if elements, err = childValues(value); err != nil {
+				return nil, err
+			}
+		}
+		if sel.kind == indexSelector {
+			index := sel.index
+			if index < 0 {
+				index += len(elements)
+			}
+			if index >= 0 && index < len(elements) {
+				result = append(result, elements[index])
+			}
+			continue
+		}
+		start, end := sliceBound(sel.start, 0, len(elements)), sliceBound(sel.end, len(elements), len(elements))
+		for index := start; index < end; index++ {
+			result = append(result, elements[index])
+		}
+	}
+	return result, nil
+}
+
+// sliceBound returns the slice bound b normalized for an array of length l, or def if b is nil.
+func sliceBound(b *int, def, l int) int {
+	if b == nil {
+		return def
+	}
+	result := *b
+	if result < 0 {
+		result += l
+	}
+	if result < 0 {
+		return 0
+	}
+	if result > l {
+		return l
+	}
+	return result
+}
+
+// childValues returns the member values of the object, or the elements of the array, value.
+func childValues(value []byte) (result [][]byte, err error) {
+	switch value[0] {
+	case '{':
+		err = eachMember(value, func(key string, v []byte) bool {
+			result = append(result, v)
+			return true
+		})
+	case '[':
+		err = eachElement(value, func(index int, v []byte) bool {
+			result = append(result, v)
+			return true
+		})
+	}
+	return
+}
+
+func parseJSONPath(path string) (segments []pathSegment, err error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("json: JSONPath %#v doesn't start with $", path)
+	}
+	rest := path[1:]
+	for rest != "" {
+		seg := pathSegment{}
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.descendant = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				if seg.selectors, rest, err = parseBracket(path, rest); err != nil {
+					return
+				}
+			} else if seg.selectors, rest, err = parseDotted(path, rest); err != nil {
+				return
+			}
+		case rest[0] == '.':
+			if seg.selectors, rest, err = parseDotted(path, rest[1:]); err != nil {
+				return
+			}
+		case rest[0] == '[':
+			if seg.selectors, rest, err = parseBracket(path, rest); err != nil {
+				return
+			}
+		default:
+			return nil, fmt.Errorf("json: unexpected %#v in JSONPath %#v", rest, path)
+		}
+		segments = append(segments, seg)
+	}
+	return
+}
+
+// parseDotted parses the name or * following a dot.
+func parseDotted(path, s string) (selectors []pathSelector, rest string, err error) {
+	end := strings.IndexAny(s, ".[")
+	if end == -1 {
+		end = len(s)
+	}
+	switch name := s[:end]; name {
+	case "":
+		err = fmt.Errorf("json: missing name in JSONPath %#v", path)
+	case "*":
+		selectors = []pathSelector{{kind: wildcardSelector}}
+	default:
+		selectors = []pathSelector{{kind: nameSelector, name: name}}
+	}
+	return selectors, s[end:], err
+}
+
+// parseBracket parses the comma separated selectors inside the brackets s starts with.
+func parseBracket(path, s string) (selectors []pathSelector, rest string, err error) {
+	rest = s[1:]
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		if rest == "" {
+			return nil, "", fmt.Errorf("json: unterminated [ in JSONPath %#v", path)
+		}
+		var sel pathSelector
+		if rest[0] == '\'' || rest[0] == '"' {
+			if sel, rest, err = parseQuotedName(path, rest); err != nil {
+				return
+			}
+		} else {
+			end := strings.IndexAny(rest, ",]")
+			if end == -1 {
+				return nil, "", fmt.Errorf("json: unterminated [ in JSONPath %#v", path)
+			}
+			if sel, err = parseIndexSelector(path, strings.TrimSpace(rest[:end])); err != nil {
+				return
+			}
+			rest = rest[end:]
+		}
+		selectors = append(selectors, sel)
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, "]") {
+			return selectors, rest[1:], nil
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return nil, "", fmt.Errorf("json: expected , or ] in JSONPath %#v", path)
+		}
+		rest = rest[1:]
+	}
+}
+
+func parseQuotedName(path, s string) (sel pathSelector, rest string, err error) {
+	quote := s[0]
+	buf := &bytes.Buffer{}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return pathSelector{kind: nameSelector, name: buf.String()}, s[i+1:], nil
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	return sel, "", fmt.Errorf("json: unterminated name in JSONPath %#v", path)
+}
+
+func parseIndexSelector(path, s string) (sel pathSelector, err error) {
+	if s == "*" {
+		sel.kind = wildcardSelector
+		return
+	}
+	if colon := strings.Index(s, ":"); colon != -1 {
+		sel.kind = sliceSelector
+		if sel.start, err = parseBound(path, s[:colon]); err != nil {
+			return
+		}
+		sel.end, err = parseBound(path, s[colon+1:])
+		return
+	}
+	sel.kind = indexSelector
+	if sel.index, err = strconv.Atoi(s); err != nil {
+		err = fmt.Errorf("json: invalid selector %#v in JSONPath %#v", s, path)
+	}
+	return
+}
+
+func parseBound(path, s string) (*int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("json: invalid slice bound %#v in JSONPath %#v", s, path)
+	}
+	return &b, nil
+}
+
+func skipSpace(data []byte) []byte {
+	for len(data) > 0 && isSpace(rune(data[0])) {
+		data = data[1:]
+	}
+	return data
+}
+
+func queryError(data, rest []byte, expected string) error {
+	offset := len(data) - len(rest)
+	if len(rest) == 0 {
+		return &SyntaxError{"unexpected end of JSON input", int64(offset)}
+	}
+	return &SyntaxError{fmt.Sprintf("invalid character %v %v", quoteChar(int(rest[0])), expected), int64(offset)}
+}
+
+// eachMember calls f with the key and raw value of each member of the object data, until f returns false.
+// The members are scanned, but not decoded.
+func eachMember(data []byte, f func(key string, value []byte) bool) error {
+	var scan scanner
+	rest := skipSpace(data[1:])
+	if len(rest) > 0 && rest[0] == '}' {
+		return nil
+	}
+	for {
+		item, r, err := nextValue(rest, &scan)
+		if err != nil {
+			return err
+		}
+		key, ok := unquote(bytes.TrimSpace(item))
+		if !ok {
+			return queryError(data, rest, "looking for beginning of object key string")
+		}
+		r = skipSpace(r)
+		if len(r) == 0 || r[0] != ':' {
+			return queryError(data, r, "after object key")
+		}
+		value, r, err := nextValue(r[1:], &scan)
+		if err != nil {
+			return err
+		}
+		if !f(key, bytes.TrimSpace(value)) {
+			return nil
+		}
+		r = skipSpace(r)
+		if len(r) > 0 && r[0] == '}' {
+			return nil
+		}
+		if len(r) == 0 || r[0] != ',' {
+			return queryError(data, r, "after object key:value pair")
+		}
+		rest = r[1:]
+	}
+}
+
+// eachElement calls f with the index and raw value of each element of the array data, until f returns false.
+// The elements are scanned, but not decoded.
+func eachElement(data []byte, f func(index int, value []byte) bool) error {
+	var scan scanner
+	rest := skipSpace(data[1:])
+	if len(rest) > 0 && rest[0] == ']' {
+		return nil
+	}
+	for index := 0; ; index++ {
+		value, r, err := nextValue(rest, &scan)
+		if err != nil {
+			return err
+		}
+		if !f(index, bytes.TrimSpace(value)) {
+			return nil
+		}
+		r = skipSpace(r)
+		if len(r) > 0 && r[0] == ']' {
+			return nil
+		}
+		if len(r) == 0 || r[0] != ',' {
+			return queryError(data, r, "after array element")
+		}
+		rest = r[1:]
+	}
+}
@@ -0,0 +1,100 @@
+package json
+
+import (
+	"reflect"
+	"testing"
+)
+
+const queryDoc = ` {
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99}
+		],
+		"bicycle": {"color": "red", "price": 19.95}
+	},
+	"a/b": {"m~n": [true, null]},
+	"": "empty"
+} `
+
+func TestGet(t *testing.T) {
+	for pointer, want := range map[string]string{
+		"":                       queryDoc[1 : len(queryDoc)-1],
+		"/store/book/1/author":   `"Evelyn Waugh"`,
+		"/store/bicycle":         `{"color": "red", "price": 19.95}`,
+		"/store/book/2/price":    `8.99`,
+		"/a~1b/m~0n/1":           `null`,
+		"/":                      `"empty"`,
+		"/store/book/0/category": `"reference"`,
+	} {
+		got, found, err := RawMessage(queryDoc).Get(pointer)
+		if err != nil || !found {
+			t.Fatalf("%v: wanted %v, got %v, %v, %v", pointer, want, string(got), found, err)
+		}
+		if string(got) != want {
+			t.Errorf("%v: wanted %v, got %v", pointer, want, string(got))
+		}
+	}
+	for _, pointer := range []string{"/missing", "/store/book/3", "/store/bicycle/color/x", "/a~1b/m~0n/2"} {
+		if got, found, err := RawMessage(queryDoc).Get(pointer); err != nil || found {
+			t.Errorf("%v: wanted nothing, got %v, %v, %v", pointer, string(got), found, err)
+		}
+	}
+	for _, pointer := range []string{"store", "/store/book/01", "/store/book/-"} {
+		if _, _, err := RawMessage(queryDoc).Get(pointer); err == nil {
+			t.Errorf("%v: wanted an error", pointer)
+		}
+	}
+
+	// Only the values along the pointer are scanned.
+	broken := RawMessage(`{"a": {"b": 1}, "c": [1, 2}`)
+	if got, found, err := broken.Get("/a/b"); err != nil || !found || string(got) != "1" {
+		t.Errorf("Wanted 1, got %v, %v, %v", string(got), found, err)
+	}
+	if _, _, err := broken.Get("/c/0"); err == nil {
+		t.Errorf("Wanted a syntax error")
+	}
+
+	var author string
+	if found, err := RawMessage(queryDoc).GetInto("/store/book/0/author", &author); err != nil || !found || author != "Nigel Rees" {
+		t.Errorf("Wanted Nigel Rees, got %v, %v, %v", author, found, err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	for path, want := range map[string][]string{
+		"$.store.book[*].author":     {`"Nigel Rees"`, `"Evelyn Waugh"`, `"Herman Melville"`},
+		"$..author":                  {`"Nigel Rees"`, `"Evelyn Waugh"`, `"Herman Melville"`},
+		"$.store.*.color":            {`"red"`},
+		"$..price":                   {`8.95`, `12.99`, `8.99`, `19.95`},
+		"$..book[2].title":           {`"Moby Dick"`},
+		"$..book[-1].isbn":           {`"0-553-21311-3"`},
+		"$..book[:2].price":          {`8.95`, `12.99`},
+		"$..book[1:].price":          {`12.99`, `8.99`},
+		"$..book[0,2]['title']":      {`"Sayings of the Century"`, `"Moby Dick"`},
+		"$['a/b'][\"m~n\"][0]":       {`true`},
+		"$.store.book[*].isbn":       {`"0-553-21311-3"`},
+		"$.missing":                  nil,
+		"$.store.book.title":         nil,
+		"$..book[5]":                 nil,
+		"$['store']['bicycle', 'x']": {`{"color": "red", "price": 19.95}`},
+	} {
+		got, err := RawMessage(queryDoc).Query(path)
+		if err != nil {
+			t.Fatalf("%v: %v", path, err)
+		}
+		var gotStrings []string
+		for _, value := range got {
+			gotStrings = append(gotStrings, string(value))
+		}
+		if !reflect.DeepEqual(gotStrings, want) {
+			t.Errorf("%v: wanted %v, got %v", path, want, gotStrings)
+		}
+	}
+	for _, path := range []string{"store", "$.", "$[", "$[x]", "$['a'", "$.a[1:x]", "$x"} {
+		if _, err := RawMessage(queryDoc).Query(path); err == nil {
+			t.Errorf("%v: wanted an error", path)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+/*
+Package schema generates JSON Schema (draft 2020-12) documents describing how the json package encodes Go types, and
+validates JSON documents against them.
+*/
+package schema
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soundtrackyourbrand/utils/json"
+)
+
+const (
+	Draft = "https://json-schema.org/draft/2020-12/schema"
+)
+
+/*
+Enumerated types have a limited set of values, that will be used as the enum of their schemas.
+*/
+type Enumerated interface {
+	JSONEnum() []interface{}
+}
+
+/*
+Schema is a JSON Schema, limited to the keywords that Generator produces and Validate checks.
+*/
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        Types  `json:"type,omitempty"`
+	// Format is 'date-time' for time.Time.
+	Format string `json:"format,omitempty"`
+	// ContentEncoding is 'base64' for []byte and fields with the base64 jsonTo directive.
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              json.Number        `json:"minimum,omitempty"`
+	Maximum              json.Number        `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	// Scopes maps the contexts of the '<context>_scopes' tags of a struct field to the scopes allowed to update it.
+	Scopes map[string][]string `json:"x-scopes,omitempty"`
+	Defs   map[string]*Schema  `json:"$defs,omitempty"`
+}
+
+/*
+Types is the 'type' keyword, encoded as a string when there is only one type.
+*/
+type Types []string
+
+func (self Types) MarshalJSON(args ...interface{}) ([]byte, error) {
+	if len(self) == 1 {
+		return json.Marshal(self[0])
+	}
+	return json.Marshal([]string(self))
+}
+
+func (self *Types) UnmarshalJSON(b []byte, args ...interface{}) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*self = Types{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(self))
+}
+
+func (self Types) has(name string) bool {
+	for _, t := range self {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	enumeratedType      = reflect.TypeOf((*Enumerated)(nil)).Elem()
+	marshalerType       = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	simpleMarshalerType = reflect.TypeOf((*json.SimpleMarshaler)(nil)).Elem()
+	generatedType       = reflect.TypeOf((*json.GeneratedMarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+var knownSchemas = map[reflect.Type]Schema{
+	reflect.TypeOf(time.Time{}):                {Type: Types{"string"}, Format: "date-time"},
+	reflect.TypeOf(time.Duration(0)):           {Type: Types{"integer"}, Description: "Duration in nanoseconds"},
+	reflect.TypeOf(json.Number("")):            {Type: Types{"number"}},
+	reflect.TypeOf(json.RawMessage{}):          {},
+	reflect.TypeOf([]byte{}):                   {Type: Types{"string"}, ContentEncoding: "base64"},
+	reflect.TypeOf((*interface{})(nil)).Elem(): {},
+}
+
+var jsonToSchemas = map[string]Schema{
+	json.JSONToString:     {Type: Types{"string"}},
+	json.JSONToInt:        {Type: Types{"integer"}},
+	json.JSONToFloat:      {Type: Types{"number"}},
+	json.JSONToBase64:     {Type: Types{"string"}, ContentEncoding: "base64"},
+	json.JSONToUnixMillis: {Type: Types{"integer"}, Description: "Milliseconds since the Unix epoch"},
+}
+
+/*
+Generator generates schemas for Go types.
+
+Object properties are the fields Marshal encodes, described by their 'jsonDoc' tags, restricted to the comma separated values
+of their 'jsonEnum' tags, and annotated with their '<context>_scopes' tags as 'x-scopes'.
+*/
+type Generator struct {
+	/*
+		Contexts, if set, make the schemas describe request bodies in the scope contexts, like HTTP methods, of the tags
+		'<context>_scopes':
+
+		Only the properties that Scopes may update in one of the Contexts are included, like the input types documented by
+		the dochandler.
+
+		Since DecodeJSON accepts partial documents and ignores nulls, properties are only required if their json tag has
+		the 'required' option, and null is accepted everywhere.
+
+		Otherwise the schemas describe the output of Marshal, where all fields without the 'omitempty' option are required.
+	*/
+	Contexts []string
+	Scopes   []string
+	// APIVersion, if set, makes the schemas describe the fields, and their names, of that API version.
+	APIVersion int
+}
+
+/*
+For returns the schema for the output of Marshal for the type of v.
+*/
+func For(v interface{}) *Schema {
+	return Generator{}.Generate(reflect.TypeOf(v))
+}
+
+type defKey struct {
+	t       reflect.Type
+	allowed bool
+}
+
+type generation struct {
+	Generator
+	refs map[defKey]string
+	defs map[string]*Schema
+}
+
+/*
+Generate returns the schema for t.
+
+Named struct types are defined once in '$defs' and referenced from everywhere else, and references to the type of the
+document itself point to '#'.
+*/
+func (self Generator) Generate(t reflect.Type) (result *Schema) {
+	g := &generation{
+		Generator: self,
+		refs:      map[defKey]string{},
+		defs:      map[string]*Schema{},
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && !isCustom(t) && t.Name() != "" {
+		g.refs[defKey{t, false}] = "#"
+		result = g.object(t, false)
+	} else {
+		result = g.schema(t, false)
+	}
+	result.Schema = Draft
+	if len(g.defs) > 0 {
+		result.Defs = g.defs
+	}
+	return
+}
+
+func (self *generation) input() bool {
+	return len(self.Contexts) > 0
+}
+
+// isCustom returns whether t has its own JSON encoding.
+func isCustom(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(generatedType) {
+		return false
+	}
+	for _, i := range []reflect.Type{marshalerType, simpleMarshalerType} {
+		if t.Implements(i) || reflect.PtrTo(t).Implements(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *generation) schema(t reflect.Type, allowed bool) (result *Schema) {
+	if known, found := knownSchemas[t]; found {
+		return &known
+	}
+	if t.Kind() == reflect.Ptr {
+		return nullable(self.schema(t.Elem(), allowed))
+	}
+	defer func() {
+		if t.Implements(enumeratedType) {
+			result.Enum = reflect.Zero(t).Interface().(Enumerated).JSONEnum()
+		}
+	}()
+	if isCustom(t) {
+		return &Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: Types{"string"}}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := uint(t.Bits())
+		return &Schema{
+			Type:    Types{"integer"},
+			Minimum: json.Number(strconv.FormatInt(-1<<(bits-1), 10)),
+			Maximum: json.Number(strconv.FormatInt(1<<(bits-1)-1, 10)),
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{
+			Type:    Types{"integer"},
+			Minimum: "0",
+			Maximum: json.Number(strconv.FormatUint(1<<uint(t.Bits())-1, 10)),
+		}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, ContentEncoding: "base64"}
+		}
+		return nullable(&Schema{Type: Types{"array"}, Items: self.schema(t.Elem(), allowed)})
+	case reflect.Array:
+		return &Schema{Type: Types{"array"}, Items: self.schema(t.Elem(), allowed)}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return &Schema{Description: fmt.Sprintf("%v can't be encoded", t)}
+		}
+		return nullable(&Schema{Type: Types{"object"}, AdditionalProperties: self.schema(t.Elem(), allowed)})
+	case reflect.Struct:
+		if t.Name() == "" {
+			return self.object(t, allowed)
+		}
+		key := defKey{t, allowed}
+		if ref, found := self.refs[key]; found {
+			return &Schema{Ref: ref}
+		}
+		name := t.Name()
+		for i := 2; self.defs[name] != nil; i++ {
+			name = fmt.Sprintf("%v%v", t.Name(), i)
+		}
+		self.refs[key] = "#/$defs/" + name
+		// Define it before generating the object, to let it refer to itself.
+		def := &Schema{}
+		self.defs[name] = def
+		*def = *self.object(t, allowed)
+		return &Schema{Ref: self.refs[key]}
+	}
+	return &Schema{Description: fmt.Sprintf("%v can't be encoded", t)}
+}
+
+// nullable returns s modified to also accept null.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: Types{"null"}}}}
+	}
+	if len(s.Type) > 0 && !s.Type.has("null") {
+		s.Type = append(s.Type, "null")
+		if len(s.Enum) > 0 {
+			s.Enum = append(s.Enum, nil)
+		}
+	}
+	return s
+}
+
+func (self *generation) object(t reflect.Type, allowed bool) (result *Schema) {
+	result = &Schema{
+		Type:       Types{"object"},
+		Properties: map[string]*Schema{},
+	}
+	for _, f := range json.Fields(t) {
+		name, ok := f.NameAt(self.APIVersion)
+		if !ok {
+			continue
+		}
+		sf := t.FieldByIndex(f.Index)
+		fieldAllowed := allowed
+		if self.input() {
+			fieldAllowed = false
+			for _, context := range self.Contexts {
+				if json.FieldAllowed(t, f.Index, allowed, context, self.Scopes...) {
+					fieldAllowed = true
+					break
+				}
+			}
+		}
+		prop := self.field(f, sf, fieldAllowed)
+		if self.input() {
+			// Fields that may not be updated are still included if some of their own fields may.
+			if !fieldAllowed && !self.hasProperties(prop) {
+				continue
+			}
+			if _, opts := parseTag(sf.Tag.Get("json")); opts["required"] {
+				result.Required = append(result.Required, name)
+			}
+			prop = nullable(prop)
+		} else if !f.OmitEmpty {
+			result.Required = append(result.Required, name)
+		}
+		result.Properties[name] = prop
+	}
+	return
+}
+
+func (self *generation) field(f json.Field, sf reflect.StructField, allowed bool) (result *Schema) {
+	if to, found := jsonToSchemas[f.To]; found {
+		result = &to
+	} else if f.Quoted && isQuotable(f.Type) {
+		result = &Schema{Type: Types{"string"}}
+	} else {
+		result = self.schema(f.Type, allowed)
+	}
+	if doc := sf.Tag.Get("jsonDoc"); doc != "" {
+		result.Description = doc
+	}
+	if enum := sf.Tag.Get("jsonEnum"); enum != "" {
+		result.Enum = nil
+		for _, value := range strings.Split(enum, ",") {
+			var v interface{} = value
+			if !result.Type.has("string") {
+				if err := json.Unmarshal([]byte(value), &v); err != nil {
+					v = value
+				}
+			}
+			result.Enum = append(result.Enum, v)
+		}
+		if result.Type.has("null") {
+			result.Enum = append(result.Enum, nil)
+		}
+	}
+	result.Scopes = scopeTags(sf.Tag)
+	return
+}
+
+func isQuotable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// hasProperties returns whether s, or the schema it refers to, describes objects with properties.
+func (self *generation) hasProperties(s *Schema) bool {
+	if s.Ref != "" {
+		if def := self.defs[strings.TrimPrefix(s.Ref, "#/$defs/")]; def != nil {
+			s = def
+		}
+	}
+	for _, alternative := range s.AnyOf {
+		if self.hasProperties(alternative) {
+			return true
+		}
+	}
+	return len(s.Properties) > 0 || (s.AdditionalProperties != nil && self.hasProperties(s.AdditionalProperties)) || (s.Items != nil && self.hasProperties(s.Items))
+}
+
+// parseTag splits a json tag into its name and options.
+func parseTag(tag string) (name string, opts map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts = map[string]bool{}
+	for _, opt := range parts[1:] {
+		opts[opt] = true
+	}
+	return parts[0], opts
+}
+
+// scopeTags returns the scopes of all '<context>_scopes' tags in tag, by context.
+func scopeTags(tag reflect.StructTag) (result map[string][]string) {
+	s := string(tag)
+	for {
+		s = strings.TrimLeft(s, " ")
+		colon := strings.Index(s, ":\"")
+		if colon < 1 {
+			break
+		}
+		key := s[:colon]
+		s = s[colon+1:]
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			break
+		}
+		value, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			break
+		}
+		s = s[end+1:]
+		if context := strings.TrimSuffix(key, "_scopes"); context != key && value != "" {
+			if result == nil {
+				result = map[string][]string{}
+			}
+			result[context] = strings.Split(value, ",")
+		}
+	}
+	return
+}
@@ -0,0 +1,185 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soundtrackyourbrand/utils/json"
+)
+
+type color string
+
+func (self color) JSONEnum() []interface{} {
+	return []interface{}{"red", "green"}
+}
+
+type Node struct {
+	Name     string    `json:"name" jsonDoc:"The name" PUT_scopes:"user"`
+	Children []*Node   `json:"children,omitempty"`
+	Parent   *Node     `json:"parent,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+type Account struct {
+	Id      string            `json:"id" jsonDoc:"The id"`
+	Name    string            `json:"name,required" PUT_scopes:"user" POST_scopes:"user,admin"`
+	Count   int8              `json:"count,string"`
+	Level   uint8             `json:"level" PUT_scopes:"admin"`
+	Kind    string            `json:"kind,omitempty" jsonEnum:"a,b" PUT_scopes:"*"`
+	Color   color             `json:"color" PUT_scopes:"user"`
+	When    time.Time         `json:"when" jsonTo:"unix-millis" PUT_scopes:"user"`
+	Data    []byte            `json:"data" jsonTo:"base64"`
+	Tags    map[string]string `json:"tags"`
+	Root    *Node             `json:"root" PUT_scopes:"user"`
+	Skipped string            `json:"-"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := For(Account{})
+	if s.Schema != Draft {
+		t.Errorf("Wanted %v, got %v", Draft, s.Schema)
+	}
+	if want := []string{"id", "name", "count", "level", "color", "when", "data", "tags", "root"}; !reflect.DeepEqual(s.Required, want) {
+		t.Errorf("Wanted %v to be required, got %v", want, s.Required)
+	}
+	if _, found := s.Properties["Skipped"]; found {
+		t.Errorf("Skipped fields should not be properties")
+	}
+	b, err := json.Marshal(s.Properties["count"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"type":"string"}`; string(b) != want {
+		t.Errorf("Wanted %v, got %s", want, b)
+	}
+	for name, want := range map[string]string{
+		"id":    `{"description":"The id","type":"string"}`,
+		"name":  `{"type":"string","x-scopes":{"POST":["user","admin"],"PUT":["user"]}}`,
+		"level": `{"type":"integer","minimum":0,"maximum":255,"x-scopes":{"PUT":["admin"]}}`,
+		"kind":  `{"type":"string","enum":["a","b"],"x-scopes":{"PUT":["*"]}}`,
+		"color": `{"type":"string","enum":["red","green"],"x-scopes":{"PUT":["user"]}}`,
+		"when":  `{"description":"Milliseconds since the Unix epoch","type":"integer","x-scopes":{"PUT":["user"]}}`,
+		"data":  `{"type":"string","contentEncoding":"base64"}`,
+		"tags":  `{"type":["object","null"],"additionalProperties":{"type":"string"}}`,
+		"root":  `{"anyOf":[{"$ref":"#/$defs/Node"},{"type":"null"}],"x-scopes":{"PUT":["user"]}}`,
+	} {
+		b, err := json.Marshal(s.Properties[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%v: wanted %v, got %s", name, want, b)
+		}
+	}
+	node := s.Defs["Node"]
+	if node == nil {
+		t.Fatalf("Wanted Node in $defs, got %+v", s.Defs)
+	}
+	if ref := node.Properties["parent"].AnyOf[0].Ref; ref != "#/$defs/Node" {
+		t.Errorf("Wanted Node to refer to itself, got %v", ref)
+	}
+	if format := node.Properties["created"].Format; format != "date-time" {
+		t.Errorf("Wanted date-time, got %v", format)
+	}
+
+	if ref := For(Node{}).Properties["children"].Items.AnyOf[0].Ref; ref != "#" {
+		t.Errorf("Wanted the root to be referred to as #, got %v", ref)
+	}
+
+	b, err = json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Schema
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Properties["tags"].Type, s.Properties["tags"].Type) {
+		t.Errorf("Wanted %v, got %v", s.Properties["tags"].Type, decoded.Properties["tags"].Type)
+	}
+}
+
+func TestGenerateInput(t *testing.T) {
+	s := Generator{Contexts: []string{"PUT"}, Scopes: []string{"user"}}.Generate(reflect.TypeOf(&Account{}))
+	var names []string
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	for _, name := range []string{"name", "kind", "color", "when", "root"} {
+		if _, found := s.Properties[name]; !found {
+			t.Errorf("Wanted %v among the properties, got %v", name, names)
+		}
+	}
+	for _, name := range []string{"id", "level", "data", "tags"} {
+		if _, found := s.Properties[name]; found {
+			t.Errorf("Did not want %v among the properties, got %v", name, names)
+		}
+	}
+	if want := []string{"name"}; !reflect.DeepEqual(s.Required, want) {
+		t.Errorf("Wanted %v to be required, got %v", want, s.Required)
+	}
+	if !s.Properties["name"].Type.has("null") {
+		t.Errorf("Wanted null to be accepted, got %v", s.Properties["name"].Type)
+	}
+	// The whole Node may be updated, since root may be.
+	if node := s.Defs["Node"]; node == nil || node.Properties["created"] == nil {
+		t.Errorf("Wanted the fields of the updatable Node, got %+v", s.Defs)
+	}
+}
+
+type Versioned struct {
+	Name       string `json:"name" jsonRenamed:"3:title"`
+	Separation int    `json:"separation" jsonUntil:"2"`
+}
+
+func TestGenerateVersion(t *testing.T) {
+	for version, want := range map[int][]string{
+		0: {"name", "separation"},
+		2: {"name", "separation"},
+		3: {"title"},
+	} {
+		s := Generator{APIVersion: version}.Generate(reflect.TypeOf(Versioned{}))
+		if !reflect.DeepEqual(s.Required, want) || len(s.Properties) != len(want) {
+			t.Errorf("%v: wanted %v, got %v", version, want, s.Required)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := For(Account{})
+	valid := `{"id":"x","name":"y","count":"1","level":2,"color":"red","when":1,"data":"","tags":null,"root":{"name":"r","created":"2014-01-01T00:00:00Z","children":[{"name":"c","created":"2014-01-01T00:00:00Z"}]}}`
+	if err := s.Validate([]byte(valid)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate([]byte(strings.Replace(valid, `"name":"y"`, `"NAME":"y"`, 1))); err != nil {
+		t.Errorf("Properties should be matched case insensitively, got %v", err)
+	}
+	if err := s.Validate([]byte(`{"id":`)); err == nil {
+		t.Errorf("Wanted an error for invalid JSON")
+	}
+
+	invalid := `{"id":1,"count":1,"level":256,"kind":"c","color":"blue","when":1.5,"data":"","tags":{"a":1},"root":{"name":"r","created":"yesterday","children":[{}]}}`
+	err := s.Validate([]byte(invalid))
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Wanted a *ValidationError, got %v", err)
+	}
+	want := []Problem{
+		{"", `missing required property "name"`},
+		{"/color", `"blue" is not one of [red green]`},
+		{"/count", "expected string, got integer"},
+		{"/id", "expected string, got integer"},
+		{"/kind", `"c" is not one of [a b]`},
+		{"/level", "256 is greater than 255"},
+		{"/root/children/0", `missing required property "name"`},
+		{"/root/children/0", `missing required property "created"`},
+		{"/root/created", `"yesterday" is not a date-time`},
+		{"/tags/a", "expected string, got integer"},
+		{"/when", "expected integer, got number"},
+	}
+	if !reflect.DeepEqual(validationErr.Problems, want) {
+		t.Errorf("Wanted\n%v\ngot\n%v", want, validationErr.Problems)
+	}
+}
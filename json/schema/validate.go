@@ -0,0 +1,257 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/soundtrackyourbrand/utils/json"
+)
+
+/*
+Problem is a reason a document doesn't match a schema.
+*/
+type Problem struct {
+	// Path is a JSON Pointer to the offending value.
+	Path    string
+	Message string
+}
+
+/*
+ValidationError is returned by Validate when the document doesn't match the schema.
+*/
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (self *ValidationError) Error() string {
+	messages := make([]string, len(self.Problems))
+	for index, problem := range self.Problems {
+		messages[index] = fmt.Sprintf("#%v: %v", problem.Path, problem.Message)
+	}
+	return fmt.Sprintf("schema: %v", strings.Join(messages, ", "))
+}
+
+/*
+Validate checks the JSON document data against the schema, and returns a *ValidationError listing all problems if it doesn't match.
+
+Properties are looked up like Unmarshal looks up struct fields, preferring exact matches to case insensitive ones, so that a
+document that is valid will decode the same way it was validated.
+*/
+func (self *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	val := &validator{root: self}
+	val.validate(self, v, "")
+	if len(val.problems) > 0 {
+		return &ValidationError{Problems: val.problems}
+	}
+	return nil
+}
+
+type validator struct {
+	root       *Schema
+	problems   []Problem
+	wrongTypes int
+}
+
+// wrongType returns whether the only problem found is that the value at path has the wrong type.
+func (self *validator) wrongType(path string) bool {
+	return len(self.problems) == 1 && self.wrongTypes == 1 && self.problems[0].Path == path
+}
+
+func (self *validator) problem(path string, format string, args ...interface{}) {
+	self.problems = append(self.problems, Problem{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (self *validator) resolve(ref string) *Schema {
+	if ref == "#" {
+		return self.root
+	}
+	if strings.HasPrefix(ref, "#/$defs/") {
+		return self.root.Defs[strings.TrimPrefix(ref, "#/$defs/")]
+	}
+	return nil
+}
+
+func typeName(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(string(v), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (self *validator) validate(s *Schema, v interface{}, path string) {
+	if s.Ref != "" {
+		ref := self.resolve(s.Ref)
+		if ref == nil {
+			self.problem(path, "unknown $ref %v", s.Ref)
+			return
+		}
+		self.validate(ref, v, path)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		// Report the problems of the first alternative of the right type, instead of just that none matched.
+		var closest *validator
+		for _, alternative := range s.AnyOf {
+			sub := &validator{root: self.root}
+			if sub.validate(alternative, v, path); len(sub.problems) == 0 {
+				matched = true
+				break
+			}
+			if closest == nil && !sub.wrongType(path) {
+				closest = sub
+			}
+		}
+		if !matched {
+			if closest != nil {
+				self.problems = append(self.problems, closest.problems...)
+			} else {
+				self.problem(path, "%v matches none of the alternatives", typeName(v))
+			}
+			return
+		}
+	}
+	if len(s.Type) > 0 {
+		name := typeName(v)
+		if !s.Type.has(name) && !(name == "integer" && s.Type.has("number")) {
+			self.problem(path, "expected %v, got %v", strings.Join(s.Type, " or "), name)
+			self.wrongTypes++
+			return
+		}
+	}
+	if len(s.Enum) > 0 {
+		self.validateEnum(s, v, path)
+	}
+	switch v := v.(type) {
+	case json.Number:
+		self.validateNumber(s, v, path)
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				self.problem(path, "%#v is not a date-time", v)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for index, elem := range v {
+				self.validate(s.Items, elem, fmt.Sprintf("%v/%v", path, index))
+			}
+		}
+	case map[string]interface{}:
+		self.validateObject(s, v, path)
+	}
+}
+
+func (self *validator) validateEnum(s *Schema, v interface{}, path string) {
+	b, err := json.Marshal(v, json.Canonical)
+	if err != nil {
+		self.problem(path, "%v", err)
+		return
+	}
+	for _, allowed := range s.Enum {
+		if a, err := json.Marshal(allowed, json.Canonical); err == nil && bytes.Equal(a, b) {
+			return
+		}
+	}
+	self.problem(path, "%s is not one of %v", b, s.Enum)
+}
+
+func (self *validator) validateNumber(s *Schema, n json.Number, path string) {
+	value, _, err := big.ParseFloat(string(n), 10, 256, big.ToNearestEven)
+	if err != nil {
+		self.problem(path, "%v", err)
+		return
+	}
+	if s.Minimum != "" {
+		if min, _, err := big.ParseFloat(string(s.Minimum), 10, 256, big.ToNearestEven); err == nil && value.Cmp(min) < 0 {
+			self.problem(path, "%v is less than %v", n, s.Minimum)
+		}
+	}
+	if s.Maximum != "" {
+		if max, _, err := big.ParseFloat(string(s.Maximum), 10, 256, big.ToNearestEven); err == nil && value.Cmp(max) > 0 {
+			self.problem(path, "%v is greater than %v", n, s.Maximum)
+		}
+	}
+}
+
+// property returns the schema of the property name like Unmarshal finds struct fields.
+func (self *Schema) property(name string) *Schema {
+	if prop, found := self.Properties[name]; found {
+		return prop
+	}
+	for key, prop := range self.Properties {
+		if strings.EqualFold(key, name) {
+			return prop
+		}
+	}
+	return nil
+}
+
+func (self *validator) validateObject(s *Schema, m map[string]interface{}, path string) {
+	for _, required := range s.Required {
+		if _, found := m[required]; found {
+			continue
+		}
+		found := false
+		for key := range m {
+			if strings.EqualFold(key, required) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			self.problem(path, "missing required property %#v", required)
+		}
+	}
+	for _, key := range sortedKeys(m) {
+		prop := s.property(key)
+		if prop == nil {
+			prop = s.AdditionalProperties
+		}
+		if prop != nil {
+			self.validate(prop, m[key], path+"/"+escape(key))
+		}
+	}
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// escape escapes a JSON Pointer reference token.
+func escape(s string) string {
+	return pointerEscaper.Replace(s)
+}
+
+// sortedKeys returns the keys of m in order, to make validation problems appear in a stable order.
+func sortedKeys(m map[string]interface{}) (result []string) {
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return
+}
@@ -50,7 +50,7 @@ func (dec *Decoder) Decode(v interface{}, args ...interface{}) error {
 	// the connection is still usable since we read a complete JSON
 	// object from it before the error happened.
 	dec.d.init(dec.buf[0:n])
-	dec.d.args = args
+	dec.d.setArgs(args)
 	err = dec.d.unmarshal(v)
 
 	// Slide rest of data down.
@@ -159,11 +159,19 @@ func (enc *Encoder) Encode(v interface{}, args ...interface{}) error {
 		return enc.err
 	}
 	e := newEncodeState()
-	e.args = args
+	defer putEncodeState(e)
+	e.setArgs(args)
 	err := e.marshal(v)
 	if err != nil {
 		return err
 	}
+	if e.opts.Canonical {
+		b := append([]byte(nil), e.Bytes()...)
+		e.Reset()
+		if err = Canonicalize(&e.Buffer, b); err != nil {
+			return err
+		}
+	}
 
 	// Terminate each value with a newline.
 	// This makes the output look a little nicer
@@ -176,10 +184,6 @@ func (enc *Encoder) Encode(v interface{}, args ...interface{}) error {
 	if _, err = enc.w.Write(e.Bytes()); err != nil {
 		enc.err = err
 	}
-	/*
-		uncomment when go_appengine runs 1.3...
-		encodeStatePool.Put(e)
-	*/
 	return err
 }
 
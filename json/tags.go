@@ -5,6 +5,9 @@
 package json
 
 import (
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -42,3 +45,128 @@ func (o tagOptions) Contains(optionName string) bool {
 	}
 	return false
 }
+
+// Directives for the jsonTo struct field tag, which coerce the JSON encoding
+// of a field regardless of its Go type.
+const (
+	JSONToString     = "string"
+	JSONToInt        = "int"
+	JSONToFloat      = "float"
+	JSONToBase64     = "base64"
+	JSONToUnixMillis = "unix-millis"
+)
+
+var jsonToTypes = map[string]string{
+	JSONToString:     "string",
+	JSONToInt:        "int",
+	JSONToFloat:      "float",
+	JSONToBase64:     "string",
+	JSONToUnixMillis: "int",
+}
+
+// JSONTo returns the jsonTo directive of a struct field, and the JSON type
+// the field will be encoded as because of it. jsonType is empty if the field
+// has no, or an unknown, directive.
+func JSONTo(sf reflect.StructField) (directive, jsonType string) {
+	directive = sf.Tag.Get("jsonTo")
+	jsonType = jsonToTypes[directive]
+	return
+}
+
+/*
+FieldVersions describes in which API versions a struct field is encoded, and under which names, according to its tags:
+
+	Separation int              `json:"track_separation" jsonUntil:"3"`
+	Settings   ScheduleSettings `json:"schedule_settings" jsonSince:"4"`
+	Name       string           `json:"name" jsonRenamed:"5:display_name,7:title"`
+
+'jsonSince' is the first API version the field is included in, 'jsonUntil' the last, and 'jsonRenamed' lists the names the
+field has from some API versions on. Like unknown jsonTo directives, malformed versions are ignored.
+
+The versions apply when the Options passed along have an APIVersion. Without one, all fields are included with the names
+in their json tags.
+*/
+type FieldVersions struct {
+	// Since is the first API version the field is included in, or 0.
+	Since int
+	// Until is the last API version the field is included in, or 0.
+	Until int
+	// Renames are the names the field has from some API versions on, ordered by version.
+	Renames []FieldRename
+}
+
+// FieldRename is the name a field has from an API version on.
+type FieldRename struct {
+	Version int
+	Name    string
+}
+
+// Versions returns the versions of a struct field described by its 'jsonSince', 'jsonUntil' and 'jsonRenamed' tags.
+func Versions(sf reflect.StructField) (result FieldVersions) {
+	result.Since, _ = strconv.Atoi(sf.Tag.Get("jsonSince"))
+	result.Until, _ = strconv.Atoi(sf.Tag.Get("jsonUntil"))
+	if renamed := sf.Tag.Get("jsonRenamed"); renamed != "" {
+		for _, rename := range strings.Split(renamed, ",") {
+			colon := strings.Index(rename, ":")
+			if colon == -1 || !isValidTag(rename[colon+1:]) {
+				continue
+			}
+			version, err := strconv.Atoi(rename[:colon])
+			if err != nil || version < 1 {
+				continue
+			}
+			result.Renames = append(result.Renames, FieldRename{
+				Version: version,
+				Name:    rename[colon+1:],
+			})
+		}
+		sort.Sort(byVersion(result.Renames))
+	}
+	return
+}
+
+type byVersion []FieldRename
+
+func (x byVersion) Len() int           { return len(x) }
+func (x byVersion) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byVersion) Less(i, j int) bool { return x[i].Version < x[j].Version }
+
+// Versioned returns whether the field has any version tags.
+func (self FieldVersions) Versioned() bool {
+	return self.Since != 0 || self.Until != 0 || len(self.Renames) > 0
+}
+
+/*
+Name returns the name of a field, that has name in its json tag, in the API version, and whether the field is included in
+the version at all.
+*/
+func (self FieldVersions) Name(name string, version int) (string, bool) {
+	if version == 0 {
+		return name, true
+	}
+	if version < self.Since || (self.Until != 0 && version > self.Until) {
+		return "", false
+	}
+	for _, rename := range self.Renames {
+		if rename.Version > version {
+			break
+		}
+		name = rename.Name
+	}
+	return name, true
+}
+
+// Changes returns the API versions where the field appears, disappears or is renamed, in order.
+func (self FieldVersions) Changes() (result []int) {
+	if self.Since > 1 {
+		result = append(result, self.Since)
+	}
+	for _, rename := range self.Renames {
+		result = append(result, rename.Version)
+	}
+	if self.Until != 0 {
+		result = append(result, self.Until+1)
+	}
+	sort.Ints(result)
+	return
+}
@@ -5,6 +5,7 @@
 package json
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -26,3 +27,27 @@ func TestTagParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestVersions(t *testing.T) {
+	type versioned struct {
+		A string `jsonSince:"2" jsonUntil:"4" jsonRenamed:"5:c,3:b,x:y,6:"`
+		B string `jsonSince:"x"`
+	}
+	typ := reflect.TypeOf(versioned{})
+	versions := Versions(typ.Field(0))
+	if want := (FieldVersions{Since: 2, Until: 4, Renames: []FieldRename{{3, "b"}, {5, "c"}}}); !reflect.DeepEqual(versions, want) {
+		t.Fatalf("Wanted %+v, got %+v", want, versions)
+	}
+	for version, want := range map[int]string{0: "a", 1: "", 2: "a", 3: "b", 4: "b", 5: ""} {
+		name, ok := versions.Name("a", version)
+		if name != want || ok != (want != "") {
+			t.Errorf("%v: wanted %q, got %q, %v", version, want, name, ok)
+		}
+	}
+	if want := []int{2, 3, 5, 5}; !reflect.DeepEqual(versions.Changes(), want) {
+		t.Errorf("Wanted %v, got %v", want, versions.Changes())
+	}
+	if Versions(typ.Field(1)).Versioned() {
+		t.Errorf("Wanted malformed versions to be ignored")
+	}
+}
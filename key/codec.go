@@ -0,0 +1,173 @@
+package key
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/soundtrackyourbrand/utils/web/httpcontext"
+)
+
+// macSize is the number of bytes of the HMAC-SHA256 kept in signed keys.
+const macSize = 16
+
+/*
+Codec makes encoded keys opaque, and impossible to forge, by signing them with HMAC-SHA256 or encrypting them with AES-GCM.
+
+Encrypted keys use a nonce derived from the secret and the key, so that, like plain and signed keys, each key has a single
+encoding with a given secret, and can be used inside string IDs and compared by its encoding. The encoding reveals which
+keys are equal, but nothing else about them.
+
+Once a kind has a Codec, Encode, EncodeCompact and MarshalJSON encode its keys with it, and Decode, DecodeKind and
+UnmarshalJSON reject keys of the kind that the Codec didn't encode.
+
+The keys of the descendants of the kind are encoded with the Codec as well, since their encodings contain the keys of their
+ancestors, and could otherwise be used to read them, or be changed to point to descendants of other ancestors. A descendant
+kind with a Codec of its own uses it instead.
+*/
+type Codec struct {
+	/*
+		Secrets are the secrets keys are signed or encrypted with. The first one is used to encode, and all of them are
+		tried when decoding, so that secrets can be rotated by adding a new one first, and removing the old one when no keys
+		encoded with it are used anywhere. Keys stored in encoded form, like string IDs made by Encode, keep using the secret
+		they were encoded with.
+	*/
+	Secrets [][]byte
+	// Encrypt makes the Codec encrypt keys, to hide their contents, instead of only signing them.
+	Encrypt bool
+	// AcceptPlain makes Decode accept keys encoded without the Codec, while links made before it was set up are still around.
+	AcceptPlain bool
+}
+
+var codecs = map[string]*Codec{}
+
+/*
+SetCodec makes codec encode and decode the keys of kind. A nil codec makes the keys of kind plain again.
+*/
+func SetCodec(kind string, codec *Codec) {
+	if codec == nil {
+		delete(codecs, kind)
+		return
+	}
+	if len(codec.Secrets) == 0 {
+		panic(fmt.Errorf("the codec for %#v has no secrets", kind))
+	}
+	codecs[kind] = codec
+}
+
+// codecOf returns the Codec of k, the one of the kind of k or else of its nearest ancestor with one, or nil if none has one.
+func codecOf(k Key) *Codec {
+	for ; k != ""; k = k.Parent() {
+		if codec := codecs[k.Kind()]; codec != nil {
+			return codec
+		}
+	}
+	return nil
+}
+
+// mac returns the truncated HMAC-SHA256 of b using secret.
+func mac(secret, b []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(b)
+	return h.Sum(nil)[:macSize]
+}
+
+// aead returns the AES-GCM cipher for secret, using an AES key derived from it.
+func aead(secret []byte) (result cipher.AEAD, err error) {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte("key encryption"))
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce of size bytes for encrypting plain with secret, the truncated HMAC-SHA256 of plain using a key
+// derived from secret, so that the same key always encrypts the same way.
+func nonce(secret, plain []byte, size int) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte("key nonce"))
+	h = hmac.New(sha256.New, h.Sum(nil))
+	h.Write(plain)
+	return h.Sum(nil)[:size]
+}
+
+func (self *Codec) encode(k Key) string {
+	var b []byte
+	if self.Encrypt {
+		gcm, err := aead(self.Secrets[0])
+		if err != nil {
+			panic(err)
+		}
+		plain := k.appendElements(nil)
+		nonce := nonce(self.Secrets[0], plain, gcm.NonceSize())
+		b = append([]byte{encryptedVersion}, nonce...)
+		b = gcm.Seal(b, nonce, plain, b[:1])
+	} else {
+		b = k.appendElements([]byte{signedVersion})
+		b = append(b, mac(self.Secrets[0], b)...)
+	}
+	return compactPrefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// openSigned returns the key in b, a signed encoding, if its MAC matches one of the secrets of its Codec.
+func openSigned(b []byte) (result Key, err error) {
+	if len(b) < 1+macSize {
+		err = fmt.Errorf("too short signed key")
+		return
+	}
+	signed, sum := b[:len(b)-macSize], b[len(b)-macSize:]
+	if result, err = readElements(signed[1:]); err != nil {
+		return
+	}
+	if codec := codecOf(result); codec != nil && !codec.Encrypt {
+		for _, secret := range codec.Secrets {
+			if hmac.Equal(mac(secret, signed), sum) {
+				return
+			}
+		}
+	}
+	result = ""
+	err = fmt.Errorf("invalid signature")
+	return
+}
+
+// openEncrypted returns the key in b, an encrypted encoding, if one of the secrets of the Codecs of any kind decrypts it.
+func openEncrypted(b []byte) (result Key, err error) {
+	for _, codec := range codecs {
+		if !codec.Encrypt {
+			continue
+		}
+		for _, secret := range codec.Secrets {
+			gcm, err := aead(secret)
+			if err != nil || len(b) < 1+gcm.NonceSize() {
+				continue
+			}
+			nonce := b[1 : 1+gcm.NonceSize()]
+			plain, err := gcm.Open(nil, nonce, b[1+gcm.NonceSize():], b[:1])
+			if err != nil {
+				continue
+			}
+			// the key must also be one the Codec that encrypted it is used for
+			if result, err = readElements(plain); err == nil && codecOf(result) == codec {
+				return result, nil
+			}
+		}
+	}
+	result = ""
+	err = fmt.Errorf("unable to decrypt key")
+	return
+}
+
+// checkPlain returns an error if k, which wasn't encoded by a Codec, has a Codec that doesn't accept that.
+func checkPlain(k Key) error {
+	if codec := codecOf(k); codec != nil && !codec.AcceptPlain {
+		err := fmt.Errorf("%v must be encoded by its codec", k.Kind())
+		return httpcontext.NewError(400, err.Error(), err.Error(), err)
+	}
+	return nil
+}
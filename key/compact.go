@@ -0,0 +1,217 @@
+package key
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/soundtrackyourbrand/utils/web/httpcontext"
+)
+
+const (
+	// compactPrefix starts compact encodings, and is never part of the legacy encodings.
+	compactPrefix = "~"
+)
+
+// The versions of the compact encoding, which are the first byte before base64.
+const (
+	compactVersion = 1 + iota
+	// signedVersion is followed by the elements and a MAC of everything before it, see Codec.
+	signedVersion
+	// encryptedVersion is followed by a nonce and the encrypted elements, see Codec.
+	encryptedVersion
+)
+
+// The low bits of the header of each element in compact encodings.
+const (
+	compactStringID = 1 << iota
+	compactIntID
+	compactFlags = iota
+)
+
+var kindCodes = map[string]uint64{}
+var codeKinds = map[uint64]string{}
+
+/*
+RegisterKindCode makes EncodeCompact write kind as code instead of spelling it out.
+
+The codes are part of the encoded keys, so once keys have been encoded with a code it must never be given to another kind, and
+all programs decoding the keys must register the same codes. Codes must be positive, and registering a kind or a code twice
+panics.
+*/
+func RegisterKindCode(kind string, code uint64) {
+	if code == 0 {
+		panic(fmt.Errorf("%#v can't have code 0", kind))
+	}
+	if existing, found := codeKinds[code]; found {
+		panic(fmt.Errorf("%#v can't have code %v, it belongs to %#v", kind, code, existing))
+	}
+	if existing, found := kindCodes[kind]; found {
+		panic(fmt.Errorf("%#v can't have code %v, it already has code %v", kind, code, existing))
+	}
+	kindCodes[kind] = code
+	codeKinds[code] = kind
+}
+
+/*
+EncodeCompact returns a URL safe encoding of the key that is usually much shorter than the one returned by Encode.
+
+The key elements are encoded in binary from the root down, with varint integer IDs and the kinds registered with
+RegisterKindCode as their codes. Decode accepts both encodings. Keys with a Codec, of their kind or an ancestor, are encoded
+by the Codec.
+*/
+func (self Key) EncodeCompact() (result string) {
+	if len(self) == 0 {
+		return
+	}
+	if codec := codecOf(self); codec != nil {
+		return codec.encode(self)
+	}
+	return compactPrefix + base64.RawURLEncoding.EncodeToString(self.appendElements([]byte{compactVersion}))
+}
+
+// appendElements appends the binary encoding of the elements of the key to buf.
+func (self Key) appendElements(buf []byte) []byte {
+	for _, element := range append(self.Ancestors(), self) {
+		kind, stringID, intID, _ := element.Split()
+		code := kindCodes[kind]
+		header := code << compactFlags
+		if stringID != "" {
+			header |= compactStringID
+		}
+		if intID != 0 {
+			header |= compactIntID
+		}
+		buf = appendUvarint(buf, header)
+		if code == 0 {
+			buf = appendBytes(buf, kind)
+		}
+		if stringID != "" {
+			buf = appendBytes(buf, stringID)
+		}
+		if intID != 0 {
+			buf = appendVarint(buf, intID)
+		}
+	}
+	return buf
+}
+
+func appendUvarint(buf []byte, i uint64) []byte {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	return append(buf, tmp[:binary.PutUvarint(tmp, i)]...)
+}
+
+func appendVarint(buf []byte, i int64) []byte {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	return append(buf, tmp[:binary.PutVarint(tmp, i)]...)
+}
+
+func appendBytes(buf []byte, s string) []byte {
+	return append(appendUvarint(buf, uint64(len(s))), s...)
+}
+
+// compactReader reads the parts of compact encodings.
+type compactReader struct {
+	buf []byte
+	err error
+}
+
+func (self *compactReader) uvarint() (result uint64) {
+	if self.err != nil {
+		return
+	}
+	result, n := binary.Uvarint(self.buf)
+	if n <= 0 {
+		self.err = fmt.Errorf("malformed varint")
+		return 0
+	}
+	self.buf = self.buf[n:]
+	return
+}
+
+func (self *compactReader) varint() (result int64) {
+	if self.err != nil {
+		return
+	}
+	result, n := binary.Varint(self.buf)
+	if n <= 0 {
+		self.err = fmt.Errorf("malformed varint")
+		return 0
+	}
+	self.buf = self.buf[n:]
+	return
+}
+
+func (self *compactReader) bytes() (result string) {
+	l := self.uvarint()
+	if self.err != nil {
+		return
+	}
+	if l > uint64(len(self.buf)) {
+		self.err = fmt.Errorf("string of %v bytes, but only %v left", l, len(self.buf))
+		return
+	}
+	result, self.buf = string(self.buf[:l]), self.buf[l:]
+	return
+}
+
+/*
+decodeCompact decodes the output of EncodeCompact, without validating the key, and returns whether it was encoded by a Codec.
+*/
+func decodeCompact(s string) (result Key, sealed bool, err error) {
+	if result, sealed, err = readCompact(strings.TrimPrefix(s, compactPrefix)); err != nil {
+		result = ""
+		err = httpcontext.NewError(400, err.Error(), err.Error(), err)
+	}
+	return
+}
+
+func readCompact(s string) (result Key, sealed bool, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return
+	}
+	if len(b) < 2 {
+		err = fmt.Errorf("too short compact key encoding %#v", s)
+		return
+	}
+	switch b[0] {
+	case compactVersion:
+		result, err = readElements(b[1:])
+	case signedVersion:
+		result, err = openSigned(b)
+		sealed = true
+	case encryptedVersion:
+		result, err = openEncrypted(b)
+		sealed = true
+	default:
+		err = fmt.Errorf("unknown compact key encoding %#v", s)
+	}
+	return
+}
+
+// readElements decodes the output of appendElements.
+func readElements(b []byte) (result Key, err error) {
+	r := &compactReader{buf: b}
+	for r.err == nil && len(r.buf) > 0 {
+		header := r.uvarint()
+		code := header >> compactFlags
+		kind := ""
+		if code == 0 {
+			kind = r.bytes()
+		} else if kind = codeKinds[code]; kind == "" {
+			r.err = fmt.Errorf("unknown kind code %v", code)
+		}
+		stringID, intID := "", int64(0)
+		if header&compactStringID != 0 {
+			stringID = r.bytes()
+		}
+		if header&compactIntID != 0 {
+			intID = r.varint()
+		}
+		result = NewWithoutValidate(kind, stringID, intID, result)
+	}
+	err = r.err
+	return
+}
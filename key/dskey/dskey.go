@@ -0,0 +1,67 @@
+/*
+dskey converts key.Key to and from the keys of the Cloud Datastore client, like gaekey does for classic App Engine.
+*/
+package dskey
+
+import (
+	"github.com/soundtrackyourbrand/utils/key"
+
+	"cloud.google.com/go/datastore"
+)
+
+func FromDatastoreErr(k *datastore.Key, err error) (result key.Key, err2 error) {
+	err2 = err
+	if err2 == nil {
+		return FromDatastore(k)
+	}
+	return
+}
+
+func FromDatastoreWithoutValidate(k *datastore.Key) (result key.Key) {
+	if k == nil {
+		return key.Key("")
+	}
+	result = key.NewWithoutValidate(k.Kind, k.Name, k.ID, FromDatastoreWithoutValidate(k.Parent))
+	return
+}
+
+func FromDatastore(k *datastore.Key) (result key.Key, err error) {
+	if k == nil {
+		return key.Key(""), nil
+	}
+	parent, err := FromDatastore(k.Parent)
+	if err != nil {
+		return
+	}
+	return key.New(k.Kind, k.Name, k.ID, parent)
+}
+
+/*
+ToDatastore returns the Cloud Datastore key for k, in namespace.
+*/
+func ToDatastore(k key.Key, namespace string) *datastore.Key {
+	if len(k) < 1 {
+		return nil
+	}
+	kind, stringID, intID, parent := k.Split()
+	return &datastore.Key{
+		Kind:      kind,
+		Name:      stringID,
+		ID:        intID,
+		Parent:    ToDatastore(parent, namespace),
+		Namespace: namespace,
+	}
+}
+
+/*
+FilterRange returns q filtered to the keys in r, in namespace, like the keys returned by key.Key.DescendantRange.
+*/
+func FilterRange(q *datastore.Query, r key.Range, namespace string) *datastore.Query {
+	if r.Start != "" {
+		q = q.Filter("__key__ >=", ToDatastore(r.Start, namespace))
+	}
+	if r.End != "" {
+		q = q.Filter("__key__ <", ToDatastore(r.End, namespace))
+	}
+	return q
+}
@@ -0,0 +1,18 @@
+package dskey
+
+import (
+	"testing"
+
+	"github.com/soundtrackyourbrand/utils/key"
+	"github.com/soundtrackyourbrand/utils/key/keytest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	keytest.RoundTrip(t, func(k key.Key) (key.Key, error) {
+		dsKey := ToDatastore(k, "ns")
+		if dsKey != nil && (dsKey.Namespace != "ns" || (dsKey.Parent != nil && dsKey.Parent.Namespace != "ns")) {
+			t.Fatalf("Wanted %v to be in the namespace", dsKey)
+		}
+		return FromDatastore(dsKey)
+	})
+}
@@ -41,3 +41,16 @@ func ToGAE(c appengine.Context, k key.Key) *datastore.Key {
 	kind, stringID, intID, parent := k.Split()
 	return datastore.NewKey(c, kind, stringID, intID, ToGAE(c, key.Key(parent)))
 }
+
+/*
+FilterRange returns q filtered to the keys in r, like the keys returned by key.Key.DescendantRange.
+*/
+func FilterRange(c appengine.Context, q *datastore.Query, r key.Range) *datastore.Query {
+	if r.Start != "" {
+		q = q.Filter("__key__ >=", ToGAE(c, r.Start))
+	}
+	if r.End != "" {
+		q = q.Filter("__key__ <", ToGAE(c, r.End))
+	}
+	return q
+}
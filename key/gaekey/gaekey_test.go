@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/soundtrackyourbrand/utils/key"
+	"github.com/soundtrackyourbrand/utils/key/keytest"
 
 	"appengine_internal"
 )
@@ -65,3 +66,9 @@ func TestFromAndToGAE(t *testing.T) {
 		}
 	}
 }
+
+func TestRoundTrip(t *testing.T) {
+	keytest.RoundTrip(t, func(k key.Key) (key.Key, error) {
+		return FromGAE(ToGAE(dummyContext{"myapp"}, k))
+	})
+}
@@ -0,0 +1,248 @@
+package key
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// The ID types a kind can be restricted to.
+const (
+	IntIDs    = "int"
+	StringIDs = "string"
+)
+
+type genealogyAssertion struct {
+	kind            string
+	parentKinds     []string
+	parentRequired  *bool
+	maxDepth        int
+	idType          string
+	stringIDPattern *regexp.Regexp
+	stringIDKinds   []string
+}
+
+/*
+StringIDKinds requires the string IDs of the kind to be encoded keys of one of the allowed kinds.
+*/
+func (self *genealogyAssertion) StringIDKinds(allowed ...string) *genealogyAssertion {
+	for _, kind := range allowed {
+		self.stringIDKinds = append(self.stringIDKinds, kind)
+	}
+	return self
+}
+
+/*
+ParentKinds requires the parents of the kind to be of one of the allowed kinds. Unless OptionalParent is also asserted, it
+requires the kind to have a parent.
+*/
+func (self *genealogyAssertion) ParentKinds(allowed ...string) *genealogyAssertion {
+	for _, kind := range allowed {
+		self.parentKinds = append(self.parentKinds, kind)
+	}
+	return self
+}
+
+/*
+RequireParent requires the kind to have a parent.
+*/
+func (self *genealogyAssertion) RequireParent() *genealogyAssertion {
+	required := true
+	self.parentRequired = &required
+	return self
+}
+
+/*
+OptionalParent allows the kind to be a root key, even if ParentKinds is asserted.
+*/
+func (self *genealogyAssertion) OptionalParent() *genealogyAssertion {
+	required := false
+	self.parentRequired = &required
+	return self
+}
+
+/*
+MaxDepth limits the number of elements, including the key itself, in keys of the kind.
+*/
+func (self *genealogyAssertion) MaxDepth(depth int) *genealogyAssertion {
+	self.maxDepth = depth
+	return self
+}
+
+/*
+IDType restricts the IDs of the kind to IntIDs or StringIDs. Keys without any ID, which are incomplete, are still allowed.
+*/
+func (self *genealogyAssertion) IDType(idType string) *genealogyAssertion {
+	if idType != IntIDs && idType != StringIDs {
+		panic(fmt.Errorf("%#v is neither IntIDs nor StringIDs", idType))
+	}
+	self.idType = idType
+	return self
+}
+
+/*
+StringIDPattern requires the string IDs of the kind, when it has one, to match the regular expression pattern.
+*/
+func (self *genealogyAssertion) StringIDPattern(pattern string) *genealogyAssertion {
+	self.stringIDPattern = regexp.MustCompile(pattern)
+	return self
+}
+
+func (self *genealogyAssertion) requiresParent() bool {
+	if self.parentRequired != nil {
+		return *self.parentRequired
+	}
+	return len(self.parentKinds) > 0
+}
+
+var genealogyAssertions = map[string]*genealogyAssertion{}
+
+func AssertedKinds() (result []string) {
+	for kind, _ := range genealogyAssertions {
+		result = append(result, kind)
+	}
+	return
+}
+
+/*
+AssertGenealogy returns the assertions about keys of kind, that New, Decode and Parse validate keys with.
+*/
+func AssertGenealogy(kind string) (result *genealogyAssertion) {
+	result, found := genealogyAssertions[kind]
+	if !found {
+		result = &genealogyAssertion{
+			kind: kind,
+		}
+		genealogyAssertions[kind] = result
+	}
+	return
+}
+
+/*
+KindSchema describes the assertions about a kind.
+*/
+type KindSchema struct {
+	Kind            string   `json:"kind"`
+	ParentKinds     []string `json:"parent_kinds,omitempty"`
+	ParentRequired  bool     `json:"parent_required"`
+	MaxDepth        int      `json:"max_depth,omitempty"`
+	IDType          string   `json:"id_type,omitempty"`
+	StringIDPattern string   `json:"string_id_pattern,omitempty"`
+	StringIDKinds   []string `json:"string_id_kinds,omitempty"`
+}
+
+/*
+AssertedSchema returns the assertions about all asserted kinds, ordered by kind.
+*/
+func AssertedSchema() (result []KindSchema) {
+	for _, kind := range AssertedKinds() {
+		assertion := genealogyAssertions[kind]
+		schema := KindSchema{
+			Kind:           kind,
+			ParentKinds:    assertion.parentKinds,
+			ParentRequired: assertion.requiresParent(),
+			MaxDepth:       assertion.maxDepth,
+			IDType:         assertion.idType,
+			StringIDKinds:  assertion.stringIDKinds,
+		}
+		if assertion.stringIDPattern != nil {
+			schema.StringIDPattern = assertion.stringIDPattern.String()
+		}
+		result = append(result, schema)
+	}
+	sort.Sort(kindSchemas(result))
+	return
+}
+
+type kindSchemas []KindSchema
+
+func (x kindSchemas) Len() int           { return len(x) }
+func (x kindSchemas) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x kindSchemas) Less(i, j int) bool { return x[i].Kind < x[j].Kind }
+
+/*
+GenealogyError is returned when a key breaks the assertions about its kind, or the kind of one of its ancestors.
+*/
+type GenealogyError struct {
+	// Key is the invalid key.
+	Key Key
+	// Element is the key, or the ancestor of it, that breaks the assertion.
+	Element Key
+	// Rule is the name of the broken assertion, like ParentKinds.
+	Rule   string
+	Reason string
+}
+
+func (self *GenealogyError) Error() string {
+	if self.Element == self.Key {
+		return fmt.Sprintf("%v breaks %v: %v", self.Key, self.Rule, self.Reason)
+	}
+	return fmt.Sprintf("%v breaks %v at its ancestor %v: %v", self.Key, self.Rule, self.Element, self.Reason)
+}
+
+// validate checks the key and all its ancestors against the assertions about their kinds.
+func (self Key) validate() (err error) {
+	for element := self; element != ""; element = element.Parent() {
+		if rule, reason := element.check(); rule != "" {
+			return &GenealogyError{
+				Key:     self,
+				Element: element,
+				Rule:    rule,
+				Reason:  reason,
+			}
+		}
+	}
+	return
+}
+
+// check returns the name of the first assertion about its kind that the key, but not necessarily its ancestors, breaks, and why.
+func (self Key) check() (rule, reason string) {
+	kind, stringID, intID, parent := self.Split()
+	assertion, found := genealogyAssertions[kind]
+	if !found {
+		return
+	}
+	if parent == "" {
+		if assertion.requiresParent() {
+			if assertion.parentRequired != nil {
+				return "RequireParent", "it has no parent"
+			}
+			return "ParentKinds", "it has no parent"
+		}
+	} else if len(assertion.parentKinds) > 0 && !contains(assertion.parentKinds, parent.Kind()) {
+		return "ParentKinds", fmt.Sprintf("its parent is a %v, not one of %v", parent.Kind(), assertion.parentKinds)
+	}
+	if assertion.maxDepth > 0 {
+		if depth := self.Depth(); depth > assertion.maxDepth {
+			return "MaxDepth", fmt.Sprintf("it has depth %v, more than %v", depth, assertion.maxDepth)
+		}
+	}
+	switch {
+	case assertion.idType == IntIDs && stringID != "":
+		return "IDType", fmt.Sprintf("it has string ID %#v, but only integer IDs are allowed", stringID)
+	case assertion.idType == StringIDs && intID != 0:
+		return "IDType", fmt.Sprintf("it has integer ID %v, but only string IDs are allowed", intID)
+	}
+	if assertion.stringIDPattern != nil && stringID != "" && !assertion.stringIDPattern.MatchString(stringID) {
+		return "StringIDPattern", fmt.Sprintf("its string ID %#v doesn't match %v", stringID, assertion.stringIDPattern)
+	}
+	if len(assertion.stringIDKinds) > 0 {
+		stringIDKey, err := Decode(stringID)
+		if err != nil {
+			return "StringIDKinds", fmt.Sprintf("its string ID %#v isn't a valid key: %v", stringID, err)
+		}
+		if !contains(assertion.stringIDKinds, stringIDKey.Kind()) {
+			return "StringIDKinds", fmt.Sprintf("its string ID is a %v key, not one of %v", stringIDKey.Kind(), assertion.stringIDKinds)
+		}
+	}
+	return
+}
+
+func contains(kinds []string, kind string) bool {
+	for _, candidate := range kinds {
+		if candidate == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -13,49 +13,8 @@ import (
 
 	"github.com/soundtrackyourbrand/utils/web/httpcontext"
 	"github.com/soundtrackyourbrand/utils/web/jsoncontext"
-	"github.com/go-errors/errors"
 )
 
-type genealogyAssertion struct {
-	kind          string
-	parentKinds   []string
-	stringIDKinds []string
-}
-
-func (self *genealogyAssertion) StringIDKinds(allowed ...string) *genealogyAssertion {
-	for _, kind := range allowed {
-		self.stringIDKinds = append(self.stringIDKinds, kind)
-	}
-	return self
-}
-
-func (self *genealogyAssertion) ParentKinds(allowed ...string) *genealogyAssertion {
-	for _, kind := range allowed {
-		self.parentKinds = append(self.parentKinds, kind)
-	}
-	return self
-}
-
-var genealogyAssertions = map[string]*genealogyAssertion{}
-
-func AssertedKinds() (result []string) {
-	for kind, _ := range genealogyAssertions {
-		result = append(result, kind)
-	}
-	return
-}
-
-func AssertGenealogy(kind string) (result *genealogyAssertion) {
-	result, found := genealogyAssertions[kind]
-	if !found {
-		result = &genealogyAssertion{
-			kind: kind,
-		}
-		genealogyAssertions[kind] = result
-	}
-	return
-}
-
 func split(s string, delim byte) (before, after string) {
 	buf := &bytes.Buffer{}
 	i := 0
@@ -63,8 +22,11 @@ func split(s string, delim byte) (before, after string) {
 		switch s[i] {
 		case '\\':
 			buf.WriteByte(s[i])
-			i++
-			buf.WriteByte(s[i])
+			// a trailing backslash escapes nothing
+			if i+1 < len(s) {
+				i++
+				buf.WriteByte(s[i])
+			}
 		case delim:
 			before, after = buf.String(), s[i+1:]
 			return
@@ -141,45 +103,7 @@ func New(kind string, stringID string, intID int64, parent Key) (result Key, err
 	return
 }
 
-func (self Key) validate() (err error) {
-	kind, stringID, _, parent := self.Split()
-	if assertion, found := genealogyAssertions[kind]; found {
-		if len(assertion.parentKinds) > 0 {
-			parentKind := parent.Kind()
-			ok := false
-			for _, okKind := range assertion.parentKinds {
-				if okKind == parentKind {
-					ok = true
-					break
-				}
-			}
-			if !ok {
-				err = errors.Errorf("%v doesn't have a valid parent", self)
-				return
-			}
-		}
-		if len(assertion.stringIDKinds) > 0 {
-			var stringIDKey = Key("")
-			if stringIDKey, err = Decode(stringID); err != nil {
-				return
-			}
-			stringIDKind := stringIDKey.Kind()
-			ok := false
-			for _, okKind := range assertion.stringIDKinds {
-				if okKind == stringIDKind {
-					ok = true
-					break
-				}
-			}
-			if !ok {
-				err = errors.Errorf("%v doesn't have a valid StringID", self)
-				return
-			}
-		}
-	}
-	return
-}
-
+// String returns the path form of the key, see Parse.
 func (self Key) String() string {
 	if len(self) == 0 {
 		return ""
@@ -211,13 +135,7 @@ func (self Key) describe(w io.Writer) {
 	}
 	kind, stringID, intID, parent := self.Split()
 	parent.describe(w)
-	fmt.Fprintf(w, "/%s,", kind)
-	if stringID != "" {
-		fmt.Fprintf(w, "%s", stringID)
-	}
-	if intID != 0 {
-		fmt.Fprintf(w, "%d", intID)
-	}
+	fmt.Fprintf(w, "/%s,%s", pathEscape(kind), pathID(stringID, intID))
 	return
 }
 
@@ -257,7 +175,18 @@ func (self Key) Parent() (result Key) {
 	return
 }
 
+/*
+Encode returns a URL safe encoding of the key, see also EncodeCompact. Keys with a Codec, of their kind or an ancestor, are
+encoded by the Codec.
+*/
 func (self Key) Encode() (result string) {
+	if codec := codecOf(self); codec != nil {
+		return codec.encode(self)
+	}
+	return self.encodePlain()
+}
+
+func (self Key) encodePlain() string {
 	return strings.Replace(base64.URLEncoding.EncodeToString([]byte(self)), "=", ".", -1)
 }
 
@@ -265,17 +194,54 @@ func DecodeKind(kind string, s string) (result Key, err error) {
 	if result, err = Decode(s); err != nil {
 		return
 	}
-	if result.Kind() != kind {
-		err = jsoncontext.NewError(417, fmt.Sprintf("Expected a key of kind %#v, but got %#v", kind, result.Kind()), "", nil)
+	return OfKind(kind, result)
+}
+
+/*
+OfKind returns k, or an error if it isn't of kind.
+*/
+func OfKind(kind string, k Key) (result Key, err error) {
+	if k.Kind() != kind {
+		err = jsoncontext.NewError(417, fmt.Sprintf("Expected a key of kind %#v, but got %#v", kind, k.Kind()), "", nil)
 		return
 	}
+	result = k
 	return
 }
 
+/*
+UnmarshalKindJSON returns the key encoded in the JSON string b, or an error if it is neither empty nor of kind. It lets the
+kind specific key types generated by keygen decode themselves.
+*/
+func UnmarshalKindJSON(kind string, b []byte) (result Key, err error) {
+	if err = result.UnmarshalJSON(b); err != nil || result == "" {
+		return
+	}
+	return OfKind(kind, result)
+}
+
+/*
+Decode returns the key encoded by Encode or EncodeCompact, and validates it, and that it was encoded by its Codec if it has
+one.
+*/
 func Decode(s string) (result Key, err error) {
 	if s == "" {
 		return
 	}
+	if strings.HasPrefix(s, compactPrefix) {
+		sealed := false
+		if result, sealed, err = decodeCompact(s); err != nil {
+			return
+		}
+		if !sealed {
+			if err = checkPlain(result); err != nil {
+				result = ""
+				return
+			}
+		}
+		err = result.validate()
+		return
+	}
 	b := []byte{}
 	b, err = base64.URLEncoding.DecodeString(strings.Replace(s, ".", "=", -1))
 	if err != nil {
@@ -283,6 +249,10 @@ func Decode(s string) (result Key, err error) {
 		return
 	}
 	result = Key(string(b))
+	if err = checkPlain(result); err != nil {
+		result = ""
+		return
+	}
 	err = result.validate()
 	return
 }
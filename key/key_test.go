@@ -1,8 +1,13 @@
 package key
 
 import (
+	"encoding/base64"
+	"fmt"
+	"math"
 	"math/rand"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -47,6 +52,7 @@ func assertSplit(t *testing.T, source, before, after string) {
 func TestSplit(t *testing.T) {
 	assertSplit(t, "apapapa/blblbl", "apapapa", "blblbl")
 	assertSplit(t, "apa\\/papa/blblbl", "apa\\/papa", "blblbl")
+	assertSplit(t, "apa\\", "apa\\", "")
 	assertSplit(t, unescape("apa\\/papa"), "apa", "papa")
 	assertSplit(t, escape("apa/gapa")+"/"+escape("gnu/hehu"), escape("apa/gapa"), escape("gnu/hehu"))
 	assertSplit(t, escape(escape("apa/gapa")+"/"+escape("gnu/hehu"))+"/"+escape(escape("ja/nej")+"/"+escape("yes/no")),
@@ -200,3 +206,362 @@ func TestNilKeys(t *testing.T) {
 		t.Fatalf("wtf")
 	}
 }
+
+func TestParse(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		var k Key
+		for j := 0; j < 3; j++ {
+			var err error
+			switch rand.Int() % 3 {
+			case 0:
+				k, err = New(randomString(), randomString(), 0, k)
+			case 1:
+				k, err = New(randomString(), "", rand.Int63()-rand.Int63(), k)
+			default:
+				k, err = New(randomString(), randomString(), rand.Int63()-rand.Int63(), k)
+			}
+			if err != nil {
+				panic(err)
+			}
+		}
+		k2, err := Parse(k.String())
+		if err != nil {
+			t.Fatalf("Failed parsing %v: %v", k, err)
+		}
+		if k != k2 {
+			t.Fatalf("%#v != %#v", k, k2)
+		}
+	}
+	for s, want := range map[string]Key{
+		"/Account,12/Location,x":    Path("Account", 12).Child("Location", "x").MustKey(),
+		`/Account,"12"/Location\,,`: Path("Account", "12").Child("Location,", "").MustKey(),
+		`/A\/b,x\\y\"`:              Path("A/b", `x\y"`).MustKey(),
+		"/Node,abc,5":               NewWithoutValidate("Node", "abc", 5, ""),
+		`/Node,"12",5`:              NewWithoutValidate("Node", "12", 5, ""),
+		"":                          "",
+	} {
+		k, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Failed parsing %v: %v", s, err)
+		}
+		if k != want || k.String() != s {
+			t.Errorf("Wanted %v, got %v", s, k)
+		}
+	}
+	for _, s := range []string{"Account,12", "/Account", "/Account,1,2", "/,12", `/Account,x\`, "/Account,1/", "/Location,x", "/Account,,2", "/Account,x,y", "/Account,x,0", "/Account,x,1,2"} {
+		if k, err := Parse(s); err == nil {
+			t.Errorf("Wanted an error parsing %v, got %#v", s, k)
+		}
+	}
+	if _, err := Path("Account", 1.5).Key(); err == nil {
+		t.Errorf("Wanted an error for a float ID")
+	}
+	if _, err := Path("Location", 1).Child("Account", 1).Key(); err == nil {
+		t.Errorf("Wanted an error for a Location without an Account parent")
+	}
+}
+
+func TestAncestors(t *testing.T) {
+	acc := Path("Account", 1).MustKey()
+	loc := Path("Account", 1).Child("Location", "x").MustKey()
+	zone := Path("Account", 1).Child("Location", "x").Child("Zone", 2).MustKey()
+	other := Path("Account", 2).Child("Location", "x").Child("Zone", 2).MustKey()
+	if zone.Root() != acc || acc.Root() != acc || Key("").Root() != "" {
+		t.Errorf("Wanted %v to be the root of %v", acc, zone)
+	}
+	if want := []Key{acc, loc}; !reflect.DeepEqual(zone.Ancestors(), want) {
+		t.Errorf("Wanted %v, got %v", want, zone.Ancestors())
+	}
+	if len(acc.Ancestors()) != 0 {
+		t.Errorf("Wanted no ancestors of %v, got %v", acc, acc.Ancestors())
+	}
+	for _, c := range []struct {
+		a, b Key
+		want bool
+	}{
+		{acc, zone, true},
+		{loc, zone, true},
+		{zone, zone, false},
+		{zone, acc, false},
+		{acc, other, false},
+		{"", acc, false},
+	} {
+		if got := c.a.IsAncestorOf(c.b); got != c.want {
+			t.Errorf("%v.IsAncestorOf(%v): wanted %v, got %v", c.a, c.b, c.want, got)
+		}
+	}
+	for k, want := range map[Key]int{"": 0, acc: 1, loc: 2, zone: 3} {
+		if got := k.Depth(); got != want {
+			t.Errorf("%v: wanted depth %v, got %v", k, want, got)
+		}
+	}
+}
+
+func TestEncodeCompact(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		k := randomKey(2)
+		enc := k.EncodeCompact()
+		k2, err := Decode(enc)
+		if err != nil {
+			t.Fatalf("Failed decoding %s: %v", enc, err)
+		}
+		if k != k2 {
+			t.Fatalf("%#v != %#v", k, k2)
+		}
+	}
+	RegisterKindCode("CompactAccount", 1001)
+	RegisterKindCode("CompactLocation", 1002)
+	k := Path("CompactAccount", 1234567).Child("CompactLocation", "x").Child("SoundZone", 89).MustKey()
+	enc := k.EncodeCompact()
+	if len(enc) >= len(k.Encode())/2 {
+		t.Errorf("Wanted %v to be much shorter than %v", enc, k.Encode())
+	}
+	for _, s := range []string{enc, k.Encode()} {
+		if k2, err := Decode(s); err != nil || k2 != k {
+			t.Errorf("Wanted %v from %v, got %v, %v", k, s, k2, err)
+		}
+	}
+	for _, s := range []string{"~", "~AA", "~AQ", "~AfxR", "~AQUC", "~AQ!", "~AQAF"} {
+		if k, err := Decode(s); err == nil {
+			t.Errorf("Wanted an error decoding %v, got %#v", s, k)
+		}
+	}
+	if _, err := Decode(NewWithoutValidate("Location", "x", 0, "").EncodeCompact()); err == nil {
+		t.Errorf("Wanted compact keys to be validated")
+	}
+}
+
+func TestCodec(t *testing.T) {
+	SetCodec("SignedAccount", &Codec{Secrets: [][]byte{[]byte("new"), []byte("old")}})
+	SetCodec("SecretAccount", &Codec{Secrets: [][]byte{[]byte("secret")}, Encrypt: true})
+	SetCodec("PlainAccount", &Codec{Secrets: [][]byte{[]byte("secret")}, AcceptPlain: true})
+	defer func() {
+		for _, kind := range []string{"SignedAccount", "SecretAccount", "PlainAccount"} {
+			SetCodec(kind, nil)
+		}
+	}()
+
+	for _, kind := range []string{"SignedAccount", "SecretAccount", "PlainAccount"} {
+		k := Path("Parent", "p").Child(kind, 12).MustKey()
+		for _, enc := range []string{k.Encode(), k.EncodeCompact()} {
+			k2, err := DecodeKind(kind, enc)
+			if err != nil || k2 != k {
+				t.Errorf("Wanted %v from %v, got %v, %v", k, enc, k2, err)
+			}
+		}
+		b, err := k.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var k2 Key
+		if err := k2.UnmarshalJSON(b); err != nil || k2 != k {
+			t.Errorf("Wanted %v from %s, got %v, %v", k, b, k2, err)
+		}
+	}
+
+	signed := Path("SignedAccount", 12).MustKey()
+	if enc := signed.Encode(); enc != signed.EncodeCompact() {
+		t.Errorf("Wanted signatures to be deterministic, got %v and %v", enc, signed.EncodeCompact())
+	}
+	// Keys signed with the old secret are still accepted.
+	old := &Codec{Secrets: [][]byte{[]byte("old")}}
+	if k, err := Decode(old.encode(signed)); err != nil || k != signed {
+		t.Errorf("Wanted %v, got %v, %v", signed, k, err)
+	}
+	secret := Path("SecretAccount", 12).MustKey()
+	if enc := secret.Encode(); enc != secret.Encode() || enc != secret.EncodeCompact() {
+		t.Errorf("Wanted encryption to be deterministic, got %v, %v and %v", enc, secret.Encode(), secret.EncodeCompact())
+	}
+	if other := Path("SecretAccount", 13).MustKey(); secret.Encode()[:20] == other.Encode()[:20] {
+		t.Errorf("Wanted different nonces for different keys, got %v and %v", secret.Encode(), other.Encode())
+	}
+	plain := Path("PlainAccount", 12).MustKey()
+	for _, enc := range []string{
+		signed.encodePlain(),
+		compactPrefix + base64.RawURLEncoding.EncodeToString(signed.appendElements([]byte{compactVersion})),
+		secret.encodePlain(),
+		(&Codec{Secrets: [][]byte{[]byte("forged")}}).encode(signed),
+		(&Codec{Secrets: [][]byte{[]byte("forged")}, Encrypt: true}).encode(secret),
+		(&Codec{Secrets: [][]byte{[]byte("secret")}}).encode(secret),
+		(&Codec{Secrets: [][]byte{[]byte("secret")}, Encrypt: true}).encode(plain),
+	} {
+		if k, err := Decode(enc); err == nil {
+			t.Errorf("Wanted an error decoding %v, got %v", enc, k)
+		}
+	}
+	if k, err := Decode(plain.encodePlain()); err != nil || k != plain {
+		t.Errorf("Wanted plain keys to be accepted, got %v, %v", k, err)
+	}
+
+	// Descendants are sealed by the Codec of their nearest ancestor with one, and only encrypted ones hide their ancestors.
+	for _, child := range []Key{Path("SignedAccount", "visible").Child("CodecChild", "l").MustKey(), Path("SecretAccount", "hidden").Child("CodecChild", "l").MustKey()} {
+		parent := child.Parent()
+		enc := child.Encode()
+		if k, err := Decode(enc); err != nil || k != child {
+			t.Errorf("Wanted %v from %v, got %v, %v", child, enc, k, err)
+		}
+		if b, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(enc, compactPrefix)); !strings.HasPrefix(enc, compactPrefix) || strings.Contains(string(b), "hidden") {
+			t.Errorf("Wanted %v to be sealed by the Codec of its parent, got %v", child, enc)
+		}
+		if k, err := Decode(child.encodePlain()); err == nil {
+			t.Errorf("Wanted an error decoding a plain %v, got %v", child, k)
+		}
+		moved := Path(parent.Kind(), "other").Child("CodecChild", "l").MustKey()
+		if k, err := Decode((&Codec{Secrets: [][]byte{[]byte("forged")}, Encrypt: parent.Kind() == "SecretAccount"}).encode(moved)); err == nil {
+			t.Errorf("Wanted an error decoding a forged %v, got %v", moved, k)
+		}
+	}
+	if k := Path("Parent", "p").Child("CodecChild", "l").MustKey(); k.Encode() != k.encodePlain() {
+		t.Errorf("Wanted keys without a Codec to stay plain, got %v", k.Encode())
+	}
+}
+
+func TestGenealogy(t *testing.T) {
+	AssertGenealogy("GenAccount").IDType(IntIDs).MaxDepth(1)
+	AssertGenealogy("GenLocation").ParentKinds("GenAccount").IDType(StringIDs).StringIDPattern("^[a-z]+$")
+	AssertGenealogy("GenZone").ParentKinds("GenLocation").OptionalParent()
+	AssertGenealogy("GenDevice").RequireParent()
+
+	account := NewWithoutValidate("GenAccount", "", 1, "")
+	device := NewWithoutValidate("GenDevice", "", 1, "")
+	upperLocation := NewWithoutValidate("GenLocation", "X", 0, account)
+	intLocation := NewWithoutValidate("GenLocation", "", 2, account)
+	for _, c := range []struct {
+		key     Key
+		rule    string
+		element Key
+	}{
+		{NewWithoutValidate("GenLocation", "x", 0, ""), "ParentKinds", ""},
+		{device, "RequireParent", ""},
+		{NewWithoutValidate("GenAccount", "x", 0, ""), "IDType", ""},
+		{NewWithoutValidate("GenAccount", "", 1, device), "MaxDepth", ""},
+		{NewWithoutValidate("GenZone", "", 1, upperLocation), "StringIDPattern", upperLocation},
+		{NewWithoutValidate("GenZone", "", 1, intLocation), "IDType", intLocation},
+		{NewWithoutValidate("GenZone", "", 1, account), "ParentKinds", ""},
+		{NewWithoutValidate("SpotifyAccount", "x", 0, ""), "StringIDKinds", ""},
+	} {
+		if c.element == "" {
+			c.element = c.key
+		}
+		err := c.key.validate()
+		genErr, ok := err.(*GenealogyError)
+		if !ok || genErr.Rule != c.rule || genErr.Element != c.element || genErr.Key != c.key {
+			t.Errorf("%v: wanted %v to be broken by %v, got %v", c.key, c.rule, c.element, err)
+		}
+	}
+	for _, k := range []Key{
+		Path("GenAccount", 1).Child("GenLocation", "x").Child("GenZone", 1).MustKey(),
+		Path("GenZone", "z").MustKey(),
+		Path("GenAccount", 1).Child("GenDevice", 1).MustKey(),
+		NewWithoutValidate("GenLocation", "", 0, account),
+	} {
+		if err := k.validate(); err != nil {
+			t.Errorf("%v: wanted no error, got %v", k, err)
+		}
+	}
+
+	var location KindSchema
+	for _, schema := range AssertedSchema() {
+		if schema.Kind == "GenLocation" {
+			location = schema
+		}
+	}
+	if want := (KindSchema{Kind: "GenLocation", ParentKinds: []string{"GenAccount"}, ParentRequired: true, IDType: StringIDs, StringIDPattern: "^[a-z]+$"}); !reflect.DeepEqual(location, want) {
+		t.Errorf("Wanted %+v, got %+v", want, location)
+	}
+}
+
+func TestUnmarshalKindJSON(t *testing.T) {
+	account := Path("Account", 1).MustKey()
+	location := Path("Account", 1).Child("Location", "x").MustKey()
+	for _, c := range []struct {
+		json string
+		want Key
+		fail bool
+	}{
+		{json: `""`},
+		{json: `null`},
+		{json: fmt.Sprintf("%q", location.Encode()), want: location},
+		{json: fmt.Sprintf("%q", location.EncodeCompact()), want: location},
+		{json: fmt.Sprintf("%q", account.Encode()), fail: true},
+		{json: `12`, fail: true},
+	} {
+		got, err := UnmarshalKindJSON("Location", []byte(c.json))
+		if (err != nil) != c.fail {
+			t.Errorf("%v: wanted failure %v, got %v", c.json, c.fail, err)
+		}
+		if got != c.want {
+			t.Errorf("%v: wanted %v, got %v", c.json, c.want, got)
+		}
+	}
+	if _, err := OfKind("Location", account); err == nil {
+		t.Errorf("Wanted an error for %v", account)
+	}
+	if got, err := OfKind("Account", account); err != nil || got != account {
+		t.Errorf("Wanted %v, got %v, %v", account, got, err)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	account := Path("Account", 2).MustKey()
+	sorted := []Key{
+		"",
+		NewWithoutValidate("Account", "", 0, ""),
+		Path("Account", int64(math.MinInt64)).MustKey(),
+		Path("Account", 1).MustKey(),
+		account,
+		Path("Account", 2).Child("Location", 1).MustKey(),
+		Path("Account", 2).Child("Location", 1).Child("Zone", "a").MustKey(),
+		Path("Account", 2).Child("Location", "a").MustKey(),
+		Path("Account", 2).Child("User", 1).MustKey(),
+		Path("Account", 3).MustKey(),
+		Path("Account", int64(math.MaxInt64)).MustKey(),
+		Path("Account", "a").MustKey(),
+		Path("Account", "ab").MustKey(),
+		Path("Account", "b").MustKey(),
+		Path("Accounts", 1).MustKey(),
+	}
+	for i, a := range sorted {
+		for j, b := range sorted {
+			want := compareInts(int64(i), int64(j))
+			if got := a.Compare(b); got != want {
+				t.Errorf("Wanted %v compared to %v to be %v, got %v", a, b, want, got)
+			}
+			if got := compareStrings(a.SortKey(), b.SortKey()); got != want {
+				t.Errorf("Wanted SortKey %v compared to %v to be %v, got %v", a.SortKey(), b.SortKey(), want, got)
+			}
+		}
+	}
+	shuffled := Keys(append([]Key{}, sorted...))
+	for i := range shuffled {
+		j := rand.Intn(i + 1)
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+	sort.Sort(shuffled)
+	if !reflect.DeepEqual([]Key(shuffled), sorted) {
+		t.Errorf("Wanted %v, got %v", sorted, shuffled)
+	}
+
+	for _, c := range []struct {
+		key       Key
+		successor Key
+	}{
+		{account, Path("Account", 3).MustKey()},
+		{Path("Account", 2).Child("Location", "a").MustKey(), Path("Account", 2).Child("Location", "a\x00").MustKey()},
+		{Path("Account", int64(math.MaxInt64)).MustKey(), Path("Account", "\x00").MustKey()},
+	} {
+		if got := c.key.Successor(); got != c.successor {
+			t.Errorf("Wanted %v, got %v", c.successor, got)
+		}
+	}
+
+	r := account.DescendantRange()
+	for i, k := range sorted {
+		if want := i >= 4 && i <= 8; r.Contains(k) != want {
+			t.Errorf("Wanted %v to contain %v: %v", r, k, want)
+		}
+		if want := i >= 4 && i <= 8; (r.Start.SortKey() <= k.SortKey() && k.SortKey() < r.End.SortKey()) != want {
+			t.Errorf("Wanted the SortKeys of %v to contain %v: %v", r, k, want)
+		}
+	}
+}
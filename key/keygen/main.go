@@ -0,0 +1,163 @@
+/*
+keygen generates key types specific to kinds, that can only hold keys of their kind, for packages that want the compiler and
+the JSON decoder to keep keys of different kinds apart.
+
+Run it with go generate, in the directory of the package that should declare the types:
+
+	//go:generate keygen -kind=Account,Location
+
+For each kind, like Account, it declares the constant AccountKind, and AccountKey, which is a key.Key of that kind, with
+NewAccountKey, AccountKeyOf and DecodeAccountKey to create them. AccountKeys encode like key.Key, but decoding JSON
+into them fails unless the key is of the kind, or empty. The kinds are the names key.For uses, the names of the types the keys
+are for.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+var (
+	kinds  = flag.String("kind", "", "comma separated list of kinds, required")
+	output = flag.String("output", "", "output file name, default <first kind>_keygen.go")
+)
+
+var keyTemplate = template.Must(template.New("keys").Parse(`// Code generated by "keygen -kind={{.Flag}}"; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/soundtrackyourbrand/utils/key"
+)
+{{range .Kinds}}
+// {{.}}Kind is the kind of {{.}}Keys.
+const {{.}}Kind = "{{.}}"
+
+// {{.}}Key is a key.Key that is either empty or of kind {{.}}.
+type {{.}}Key key.Key
+
+// New{{.}}Key returns a new, validated, key of kind {{.}}.
+func New{{.}}Key(stringID string, intID int64, parent key.Key) ({{.}}Key, error) {
+	k, err := key.New({{.}}Kind, stringID, intID, parent)
+	return {{.}}Key(k), err
+}
+
+// {{.}}KeyOf converts k to a {{.}}Key, or returns an error if it isn't of kind {{.}}.
+func {{.}}KeyOf(k key.Key) ({{.}}Key, error) {
+	k, err := key.OfKind({{.}}Kind, k)
+	return {{.}}Key(k), err
+}
+
+// Decode{{.}}Key returns the {{.}}Key encoded in s, or an error if it isn't of kind {{.}}.
+func Decode{{.}}Key(s string) ({{.}}Key, error) {
+	k, err := key.DecodeKind({{.}}Kind, s)
+	return {{.}}Key(k), err
+}
+
+// Key returns the key as a plain key.Key.
+func (self {{.}}Key) Key() key.Key {
+	return key.Key(self)
+}
+
+func (self {{.}}Key) Encode() string {
+	return key.Key(self).Encode()
+}
+
+func (self {{.}}Key) String() string {
+	return key.Key(self).String()
+}
+
+func (self {{.}}Key) StringID() string {
+	return key.Key(self).StringID()
+}
+
+func (self {{.}}Key) IntID() int64 {
+	return key.Key(self).IntID()
+}
+
+func (self {{.}}Key) Parent() key.Key {
+	return key.Key(self).Parent()
+}
+
+func (self {{.}}Key) MarshalJSON() ([]byte, error) {
+	return key.Key(self).MarshalJSON()
+}
+
+func (self *{{.}}Key) UnmarshalJSON(b []byte) error {
+	k, err := key.UnmarshalKindJSON({{.}}Kind, b)
+	if err == nil {
+		*self = {{.}}Key(k)
+	}
+	return err
+}
+{{end}}`))
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("keygen: ")
+	flag.Parse()
+	if *kinds == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	kindList := strings.Split(*kinds, ",")
+	outputName := *output
+	if outputName == "" {
+		outputName = strings.ToLower(kindList[0]) + "_keygen.go"
+	}
+	outputName = filepath.Join(dir, outputName)
+
+	pkgName, err := packageName(dir, outputName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err = keyTemplate.Execute(buf, map[string]interface{}{
+		"Flag":    *kinds,
+		"Package": pkgName,
+		"Kinds":   kindList,
+	}); err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile(outputName, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// packageName returns the name of the package in dir, ignoring the output file and tests.
+func packageName(dir, outputName string) (result string, err error) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, func(fi os.FileInfo) bool {
+		return fi.Name() != filepath.Base(outputName) && !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.PackageClauseOnly)
+	if err != nil {
+		return
+	}
+	for name := range pkgs {
+		if result != "" {
+			log.Fatalf("found both package %v and %v in %v", result, name, dir)
+		}
+		result = name
+	}
+	if result == "" {
+		log.Fatalf("found no package in %v", dir)
+	}
+	return
+}
@@ -0,0 +1,70 @@
+/*
+keytest is a test suite for adapters that convert key.Key to and from the key types of other datastores, so that key.Key can
+stay the ID used everywhere.
+*/
+package keytest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/soundtrackyourbrand/utils/key"
+)
+
+/*
+Keys returns keys that adapters must convert without loss: root keys and deep hierarchies, string IDs with the delimiters
+of the key format and non ASCII characters, string IDs that look like integers, large integer IDs, and an incomplete key.
+*/
+func Keys() []key.Key {
+	return []key.Key{
+		key.Path("Account", 1).MustKey(),
+		key.Path("Account", "a").MustKey(),
+		key.Path("Account", int64(1)<<62).Child("Location", "x").Child("SoundZone", 3).MustKey(),
+		key.Path("Account", "12").Child("Location", 12).MustKey(),
+		key.Path("Kind,with/delimiters\\", "id,with/delimiters\\").MustKey(),
+		key.Path("Åäö", "åäö ☃").Child("Account", " ").MustKey(),
+		key.NewWithoutValidate("Location", "", 0, key.Path("Account", 1).MustKey()),
+	}
+}
+
+var runes = []rune("abcXYZ019 ,/\\~.\"'åäö☃")
+
+func randomString() string {
+	result := make([]rune, 1+rand.Intn(15))
+	for index := range result {
+		result[index] = runes[rand.Intn(len(runes))]
+	}
+	return string(result)
+}
+
+// RandomKey returns a key with the given number of random ancestors, each with either a string or a positive integer ID.
+func RandomKey(ancestors int) (result key.Key) {
+	for i := 0; i <= ancestors; i++ {
+		if rand.Intn(2) == 0 {
+			result = key.NewWithoutValidate(randomString(), randomString(), 0, result)
+		} else {
+			result = key.NewWithoutValidate(randomString(), "", 1+rand.Int63n(1<<62), result)
+		}
+	}
+	return
+}
+
+/*
+RoundTrip fails t unless toAndFrom, which converts a key to the key type of an adapter and back, returns Keys and random
+keys unchanged.
+*/
+func RoundTrip(t *testing.T, toAndFrom func(k key.Key) (key.Key, error)) {
+	keys := Keys()
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, RandomKey(i%4))
+	}
+	for _, k := range keys {
+		k2, err := toAndFrom(k)
+		if err != nil {
+			t.Fatalf("Failed converting %v: %v", k, err)
+		}
+		if k2 != k {
+			t.Fatalf("Wanted %v, got %v", k, k2)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package key
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// The ranks of the ID types of elements, which sort like the datastore sorts them: incomplete first, then integer IDs, then string IDs.
+const (
+	noIDRank = iota
+	intIDRank
+	stringIDRank
+)
+
+func idRank(stringID string, intID int64) int {
+	switch {
+	case stringID != "":
+		return stringIDRank
+	case intID != 0:
+		return intIDRank
+	}
+	return noIDRank
+}
+
+/*
+Compare returns -1, 0 or 1 if the key sorts before, like or after other, in the order the datastore sorts keys.
+
+Keys are compared element by element from the root down, by kind, then by ID, where integer IDs sort before string IDs.
+Ancestors sort right before their descendants, and the empty key sorts before all other keys. Elements with both a string and
+an integer ID, which the datastore doesn't have, sort by string ID and then by integer ID.
+*/
+func (self Key) Compare(other Key) int {
+	selfElements, otherElements := append(self.Ancestors(), self), append(other.Ancestors(), other)
+	if self == "" {
+		selfElements = nil
+	}
+	if other == "" {
+		otherElements = nil
+	}
+	for i := 0; i < len(selfElements) && i < len(otherElements); i++ {
+		if result := compareElements(selfElements[i], otherElements[i]); result != 0 {
+			return result
+		}
+	}
+	return compareInts(int64(len(selfElements)), int64(len(otherElements)))
+}
+
+// Less returns whether the key sorts before other, see Compare.
+func (self Key) Less(other Key) bool {
+	return self.Compare(other) < 0
+}
+
+// compareElements compares the kinds and IDs, but not the parents, of a and b.
+func compareElements(a, b Key) int {
+	aKind, aStringID, aIntID, _ := a.Split()
+	bKind, bStringID, bIntID, _ := b.Split()
+	if result := compareStrings(aKind, bKind); result != 0 {
+		return result
+	}
+	if result := compareInts(int64(idRank(aStringID, aIntID)), int64(idRank(bStringID, bIntID))); result != 0 {
+		return result
+	}
+	if result := compareStrings(aStringID, bStringID); result != 0 {
+		return result
+	}
+	return compareInts(aIntID, bIntID)
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+/*
+Keys sorts keys with Compare.
+*/
+type Keys []Key
+
+func (self Keys) Len() int           { return len(self) }
+func (self Keys) Swap(i, j int)      { self[i], self[j] = self[j], self[i] }
+func (self Keys) Less(i, j int) bool { return self[i].Less(self[j]) }
+
+/*
+Successor returns the first key, in the order of Compare, that sorts after the key and all its descendants. It is the sibling
+of the key with the next ID: the next integer ID for integer IDs, and the string ID followed by a zero byte for string IDs.
+
+The successor isn't validated, and is meant as an exclusive upper bound of queries, not to be stored. Keys with both a string
+and an integer ID, which the datastore doesn't have, may sort between the key and its successor without being descendants.
+*/
+func (self Key) Successor() Key {
+	if self == "" {
+		return ""
+	}
+	kind, stringID, intID, parent := self.Split()
+	switch {
+	case stringID == "" && intID == 0:
+		intID = math.MinInt64
+	case stringID != "" && intID == 0:
+		stringID += "\x00"
+	case intID < math.MaxInt64:
+		intID++
+	default:
+		stringID, intID = stringID+"\x00", 0
+	}
+	return NewWithoutValidate(kind, stringID, intID, parent)
+}
+
+/*
+Range is the keys from Start, inclusive, to End, exclusive, in the order of Compare. An empty End means no upper bound.
+*/
+type Range struct {
+	Start Key
+	End   Key
+}
+
+// Contains returns whether k is inside the range.
+func (self Range) Contains(k Key) bool {
+	return self.Start.Compare(k) <= 0 && (self.End == "" || k.Less(self.End))
+}
+
+func (self Range) String() string {
+	return fmt.Sprintf("[%v, %v)", self.Start, self.End)
+}
+
+/*
+DescendantRange returns the range of the key and all its descendants, which like ancestor queries of the datastore includes the
+key itself.
+*/
+func (self Key) DescendantRange() Range {
+	return Range{
+		Start: self,
+		End:   self.Successor(),
+	}
+}
+
+/*
+SortKey returns a printable string that sorts, byte by byte, like the key sorts with Compare. It lets search engines without
+knowledge of keys, like Elasticsearch, filter indexed keys by ranges of them.
+
+Each element is a '/', the hex encoded kind, a '.', the rank of the ID type, and then the ID. Integer IDs are 16 hex digits of
+the ID offset to be positive, and string IDs the hex encoded string followed by a '.' and the integer ID. The ancestors of a key
+therefore have SortKeys that are prefixes of the SortKey of the key.
+*/
+func (self Key) SortKey() string {
+	if self == "" {
+		return ""
+	}
+	buf := &bytes.Buffer{}
+	for _, element := range append(self.Ancestors(), self) {
+		kind, stringID, intID, _ := element.Split()
+		rank := idRank(stringID, intID)
+		fmt.Fprintf(buf, "/%v.%d", hex.EncodeToString([]byte(kind)), rank)
+		if rank == stringIDRank {
+			fmt.Fprintf(buf, "%v.", hex.EncodeToString([]byte(stringID)))
+		}
+		if rank != noIDRank {
+			fmt.Fprintf(buf, "%016x", uint64(intID)^(1<<63))
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,229 @@
+package key
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// pathEscape escapes s for the path form.
+func pathEscape(s string) string {
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\', ',', '/', '"':
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+// pathUnescape reverses pathEscape.
+func pathUnescape(s string) (result string, err error) {
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			if i++; i == len(s) {
+				err = errors.Errorf("%#v ends with an unfinished escape", s)
+				return
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	result = buf.String()
+	return
+}
+
+// isIntID returns whether s is the path form of an integer ID.
+func isIntID(s string) bool {
+	i, err := strconv.ParseInt(s, 10, 64)
+	return err == nil && strconv.FormatInt(i, 10) == s
+}
+
+// pathID returns the path form of an ID, with the string and the integer ID separated by , if both are set.
+func pathID(stringID string, intID int64) string {
+	buf := &bytes.Buffer{}
+	if stringID != "" {
+		if isIntID(stringID) {
+			fmt.Fprintf(buf, "%q", stringID)
+		} else {
+			buf.WriteString(pathEscape(stringID))
+		}
+		if intID != 0 {
+			buf.WriteByte(',')
+		}
+	}
+	if intID != 0 {
+		fmt.Fprintf(buf, "%d", intID)
+	}
+	return buf.String()
+}
+
+// parseStringID returns the string ID with the path form s.
+func parseStringID(s string) (string, error) {
+	if len(s) > 1 && s[0] == '"' && s[len(s)-1] == '"' && isIntID(s[1:len(s)-1]) {
+		return s[1 : len(s)-1], nil
+	}
+	return pathUnescape(s)
+}
+
+// splitPath splits s at the delimiters that aren't escaped, leaving the escapes in the parts.
+func splitPath(s string, delim byte) (result []string) {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			result = append(result, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(result, s[start:])
+}
+
+/*
+Parse returns the key with the path form s, as rendered by String, and validates it like New does.
+
+The path form lists the kind and ID of each element from the root down, like /Account,12/Location,x. Kinds and IDs escape
+'\', ',', '/' and '"' with a backslash. IDs written as plain decimal integers are integer IDs, so string IDs that look like
+integers are quoted, like /Location,"12". Elements with both a string and an integer ID list the string ID first, like
+/Location,x,12.
+*/
+func Parse(s string) (result Key, err error) {
+	if s == "" {
+		return
+	}
+	if s[0] != '/' {
+		err = errors.Errorf("%#v doesn't start with /", s)
+		return
+	}
+	for _, element := range splitPath(s[1:], '/') {
+		parts := splitPath(element, ',')
+		if len(parts) != 2 && len(parts) != 3 {
+			err = errors.Errorf("%#v in %#v isn't a kind and an ID separated by ,", element, s)
+			return
+		}
+		kind := ""
+		if kind, err = pathUnescape(parts[0]); err != nil {
+			return
+		}
+		if kind == "" {
+			err = errors.Errorf("%#v in %#v has no kind", element, s)
+			return
+		}
+		stringID, intID := "", int64(0)
+		if len(parts) == 3 {
+			if parts[1] == "" || isIntID(parts[1]) || !isIntID(parts[2]) || parts[2] == "0" {
+				err = errors.Errorf("%#v in %#v isn't a kind, a string ID and an integer ID separated by ,", element, s)
+				return
+			}
+			if stringID, err = parseStringID(parts[1]); err != nil {
+				return
+			}
+			intID, _ = strconv.ParseInt(parts[2], 10, 64)
+		} else if isIntID(parts[1]) {
+			intID, _ = strconv.ParseInt(parts[1], 10, 64)
+		} else if stringID, err = parseStringID(parts[1]); err != nil {
+			return
+		}
+		if result, err = New(kind, stringID, intID, result); err != nil {
+			return
+		}
+	}
+	return
+}
+
+/*
+Builder builds keys one element at a time, from the root down:
+
+	k, err := key.Path("Account", 12).Child("Location", "x").Key()
+
+IDs are strings for string IDs, or integers for integer IDs. The first invalid element makes Key return an error.
+*/
+type Builder struct {
+	key Key
+	err error
+}
+
+// Path returns a Builder for a root key of kind with id.
+func Path(kind string, id interface{}) *Builder {
+	return (&Builder{}).Child(kind, id)
+}
+
+// Child adds an element of kind with id below the key built so far.
+func (self *Builder) Child(kind string, id interface{}) *Builder {
+	if self.err != nil {
+		return self
+	}
+	stringID, intID := "", int64(0)
+	switch id := id.(type) {
+	case string:
+		stringID = id
+	case int:
+		intID = int64(id)
+	case int32:
+		intID = int64(id)
+	case int64:
+		intID = id
+	default:
+		self.err = errors.Errorf("%#v is neither a string nor an integer ID", id)
+		return self
+	}
+	self.key, self.err = New(kind, stringID, intID, self.key)
+	return self
+}
+
+// Key returns the key built, or the error of the first invalid element.
+func (self *Builder) Key() (Key, error) {
+	return self.key, self.err
+}
+
+// MustKey returns the key built, and panics if any element was invalid.
+func (self *Builder) MustKey() Key {
+	if self.err != nil {
+		panic(self.err)
+	}
+	return self.key
+}
+
+// Root returns the topmost ancestor of the key, or the key itself if it has no parent.
+func (self Key) Root() Key {
+	for parent := self.Parent(); parent != ""; parent = self.Parent() {
+		self = parent
+	}
+	return self
+}
+
+// Ancestors returns the ancestors of the key, from the root down to its parent.
+func (self Key) Ancestors() (result []Key) {
+	for parent := self.Parent(); parent != ""; parent = parent.Parent() {
+		result = append([]Key{parent}, result...)
+	}
+	return
+}
+
+// IsAncestorOf returns whether the key is the parent, or an ancestor of the parent, of other.
+func (self Key) IsAncestorOf(other Key) bool {
+	if self == "" || len(self) >= len(other) || !strings.HasSuffix(string(other), string(self)) {
+		return false
+	}
+	for parent := other.Parent(); parent != ""; parent = parent.Parent() {
+		if parent == self {
+			return true
+		}
+	}
+	return false
+}
+
+// Depth returns the number of elements in the key, which is 1 for root keys and 0 for the empty key.
+func (self Key) Depth() (result int) {
+	for ; self != ""; self = self.Parent() {
+		result++
+	}
+	return
+}
@@ -0,0 +1,89 @@
+/*
+sqlkey converts key.Key to and from the composite primary keys of SQL tables, with one column per key element from the root
+down, holding the integer or string ID of the element.
+*/
+package sqlkey
+
+import (
+	"fmt"
+
+	"github.com/soundtrackyourbrand/utils/key"
+)
+
+/*
+Kinds returns the kinds of the elements of k, from the root down, which are the kinds FromTuple needs to convert the Tuple of k
+back.
+*/
+func Kinds(k key.Key) (result []string) {
+	for _, element := range append(k.Ancestors(), k) {
+		result = append(result, element.Kind())
+	}
+	return
+}
+
+/*
+Tuple returns the IDs of the elements of k, from the root down, as values for the columns of a composite primary key: int64 for
+integer IDs, string for string IDs, and nil for elements without ID.
+*/
+func Tuple(k key.Key) (result []interface{}) {
+	if k == "" {
+		return
+	}
+	for _, element := range append(k.Ancestors(), k) {
+		switch {
+		case element.StringID() != "":
+			result = append(result, element.StringID())
+		case element.IntID() != 0:
+			result = append(result, element.IntID())
+		default:
+			result = append(result, nil)
+		}
+	}
+	return
+}
+
+/*
+FromTupleWithoutValidate is like FromTuple, without validating the key.
+*/
+func FromTupleWithoutValidate(kinds []string, values ...interface{}) (result key.Key, err error) {
+	if len(kinds) != len(values) {
+		err = fmt.Errorf("got %v values for the %v kinds %v", len(values), len(kinds), kinds)
+		return
+	}
+	for index, kind := range kinds {
+		stringID, intID := "", int64(0)
+		switch value := values[index].(type) {
+		case nil:
+		case int64:
+			intID = value
+		case int:
+			intID = int64(value)
+		case int32:
+			intID = int64(value)
+		case string:
+			stringID = value
+		case []byte:
+			stringID = string(value)
+		default:
+			err = fmt.Errorf("the ID %#v of %v is neither an integer nor a string", value, kind)
+			return
+		}
+		result = key.NewWithoutValidate(kind, stringID, intID, result)
+	}
+	return
+}
+
+/*
+FromTuple returns the key with kinds, from the root down, and the IDs values, like the ones returned by Tuple or scanned from
+the columns of a composite primary key, and validates it like key.New does.
+*/
+func FromTuple(kinds []string, values ...interface{}) (result key.Key, err error) {
+	if result, err = FromTupleWithoutValidate(kinds, values...); err != nil {
+		return
+	}
+	// key.New validates the whole key
+	if result != "" {
+		result, err = key.New(result.Kind(), result.StringID(), result.IntID(), result.Parent())
+	}
+	return
+}
@@ -0,0 +1,31 @@
+package sqlkey
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soundtrackyourbrand/utils/key"
+	"github.com/soundtrackyourbrand/utils/key/keytest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	keytest.RoundTrip(t, func(k key.Key) (key.Key, error) {
+		return FromTuple(Kinds(k), Tuple(k)...)
+	})
+}
+
+func TestFromTuple(t *testing.T) {
+	want := key.Path("Account", 1).Child("Location", "x").MustKey()
+	if got, err := FromTuple([]string{"Account", "Location"}, int32(1), []byte("x")); err != nil || got != want {
+		t.Errorf("Wanted %v, got %v, %v", want, got, err)
+	}
+	if tuple := Tuple(want); !reflect.DeepEqual(tuple, []interface{}{int64(1), "x"}) {
+		t.Errorf("Wanted [1 x], got %#v", tuple)
+	}
+	if _, err := FromTuple([]string{"Account"}, 1, 2); err == nil {
+		t.Errorf("Wanted an error for too many values")
+	}
+	if _, err := FromTuple([]string{"Account"}, 1.5); err == nil {
+		t.Errorf("Wanted an error for a float ID")
+	}
+}
@@ -0,0 +1,21 @@
+/*
+multierror contains MultiError, which is also available as utils.MultiError, for packages that utils itself imports.
+*/
+package multierror
+
+import (
+	"strings"
+)
+
+/*
+MultiError is several errors, like those of things done in parallel.
+*/
+type MultiError []error
+
+func (self MultiError) Error() string {
+	s := make([]string, len(self))
+	for index, err := range self {
+		s[index] = err.Error()
+	}
+	return strings.Join(s, ", ")
+}
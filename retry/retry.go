@@ -0,0 +1,86 @@
+/*
+retry contains the backoff and attempt limits shared by the retries of commands in run and of requests in client.
+*/
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+/*
+Backoff is an exponential backoff: the first delay is Initial, and each following delay is Multiplier times longer, up to Max.
+*/
+type Backoff struct {
+	Initial time.Duration
+	// Multiplier less than 1 means 2.
+	Multiplier float64
+	// Max zero means no max.
+	Max time.Duration
+	// Jitter is the fraction, between 0 and 1, of each delay that is randomly removed from it, to keep clients failing at the
+	// same time from retrying at the same time.
+	Jitter float64
+}
+
+/*
+DefaultBackoff starts at 100ms and doubles, without jitter.
+*/
+var DefaultBackoff = Backoff{
+	Initial:    100 * time.Millisecond,
+	Multiplier: 2,
+}
+
+// Delay returns the delay after the failed attempt, counted from 0.
+func (self Backoff) Delay(attempt int) (result time.Duration) {
+	multiplier := self.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	result = self.Initial
+	for i := 0; i < attempt && (self.Max == 0 || result < self.Max); i++ {
+		result = time.Duration(float64(result) * multiplier)
+	}
+	if self.Max > 0 && result > self.Max {
+		result = self.Max
+	}
+	if self.Jitter > 0 {
+		result -= time.Duration(rand.Float64() * self.Jitter * float64(result))
+	}
+	return
+}
+
+/*
+Policy limits how many times something is attempted, and how long to wait between the attempts:
+
+	for attempt := 0; ; attempt++ {
+		if err = try(); err == nil {
+			break
+		}
+		delay, again := policy.Next(attempt)
+		if !again {
+			break
+		}
+		time.Sleep(delay)
+	}
+*/
+type Policy struct {
+	// MaxAttempts is the number of attempts at most, including the first. Less than 1 means 1.
+	MaxAttempts int
+	Backoff     Backoff
+}
+
+/*
+DefaultPolicy makes 7 attempts with DefaultBackoff.
+*/
+var DefaultPolicy = Policy{
+	MaxAttempts: 7,
+	Backoff:     DefaultBackoff,
+}
+
+// Next returns the delay before the attempt following the failed attempt, counted from 0, or false if it was the last one.
+func (self Policy) Next(attempt int) (delay time.Duration, again bool) {
+	if attempt+1 >= self.MaxAttempts {
+		return 0, false
+	}
+	return self.Backoff.Delay(attempt), true
+}
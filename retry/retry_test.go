@@ -0,0 +1,42 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	backoff := Backoff{Initial: time.Second, Max: 5 * time.Second}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := backoff.Delay(attempt); got != want {
+			t.Errorf("Wanted %v, got %v", want, got)
+		}
+	}
+	// the delays of client.DoRequest
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1600, 3200, 6400} {
+		if got := DefaultBackoff.Delay(attempt); got != want*time.Millisecond {
+			t.Errorf("Wanted %v, got %v", want*time.Millisecond, got)
+		}
+	}
+	backoff.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := backoff.Delay(1); got > 2*time.Second || got < time.Second {
+			t.Errorf("Wanted between 1s and 2s, got %v", got)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, Backoff: Backoff{Initial: time.Second}}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second} {
+		if delay, again := policy.Next(attempt); !again || delay != want {
+			t.Errorf("Wanted %v after attempt %v, got %v, %v", want, attempt, delay, again)
+		}
+	}
+	if delay, again := policy.Next(2); again || delay != 0 {
+		t.Errorf("Wanted no delay after the last attempt, got %v, %v", delay, again)
+	}
+	if _, again := (Policy{}).Next(0); again {
+		t.Errorf("Wanted a single attempt without MaxAttempts")
+	}
+}
@@ -0,0 +1,348 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/soundtrackyourbrand/utils/multierror"
+)
+
+// DefaultGrace is how long commands get to exit after SIGTERM, before they are killed, unless Grace says otherwise.
+var DefaultGrace = 5 * time.Second
+
+/*
+Cmd builds and runs a command:
+
+	stdout, stderr, err := run.Command("git", "fetch").Dir(repo).Timeout(time.Minute).Output()
+
+Unlike exec.Cmd it reads no stdin unless given some, and it can be cancelled by a context or a timeout, which first sends
+SIGTERM to the command and everything it started, and then SIGKILL if they are still running after the grace period.
+Cancellable commands run in their own process group, so they don't get the signals of the terminal, like the interrupt of ^C,
+and stop if they read from it.
+*/
+type Cmd struct {
+	path    string
+	args    []string
+	ctx     context.Context
+	timeout time.Duration
+	grace   time.Duration
+	dir     string
+	env     []string
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+
+	stderrFatal bool
+	host        string
+	logger      Logger
+	redactions  []Redaction
+	executor    Executor
+	retry       *Retry
+}
+
+// Command returns a Cmd running path with args, writing to os.Stdout and os.Stderr.
+func Command(path string, args ...string) *Cmd {
+	return &Cmd{
+		path:   path,
+		args:   args,
+		ctx:    context.Background(),
+		grace:  DefaultGrace,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		logger: DefaultLogger,
+	}
+}
+
+// Context makes the command stop when ctx is done.
+func (self *Cmd) Context(ctx context.Context) *Cmd {
+	self.ctx = ctx
+	return self
+}
+
+// Timeout makes the command stop when it has run for d.
+func (self *Cmd) Timeout(d time.Duration) *Cmd {
+	self.timeout = d
+	return self
+}
+
+// Grace sets how long the command gets to exit after SIGTERM, before it is killed.
+func (self *Cmd) Grace(d time.Duration) *Cmd {
+	self.grace = d
+	return self
+}
+
+// Dir makes the command run in dir.
+func (self *Cmd) Dir(dir string) *Cmd {
+	self.dir = dir
+	return self
+}
+
+// Env adds environment variables, like "KEY=value", to the environment of the current process that the command gets.
+func (self *Cmd) Env(env ...string) *Cmd {
+	self.env = append(self.env, env...)
+	return self
+}
+
+// Stdin makes the command read r, for example os.Stdin to let it read from the terminal.
+func (self *Cmd) Stdin(r io.Reader) *Cmd {
+	self.stdin = r
+	return self
+}
+
+// Stdout makes the command write its stdout to w.
+func (self *Cmd) Stdout(w io.Writer) *Cmd {
+	self.stdout = w
+	return self
+}
+
+// Stderr makes the command write its stderr to w.
+func (self *Cmd) Stderr(w io.Writer) *Cmd {
+	self.stderr = w
+	return self
+}
+
+// StderrFatal makes the command fail if it writes anything but whitespace to stderr, even if it exits successfully.
+func (self *Cmd) StderrFatal() *Cmd {
+	self.stderrFatal = true
+	return self
+}
+
+// Executor makes e start the process of the command, instead of DefaultExecutor.
+func (self *Cmd) Executor(e Executor) *Cmd {
+	self.executor = e
+	return self
+}
+
+// Retry makes the command run again, according to policy, when it fails.
+func (self *Cmd) Retry(policy Retry) *Cmd {
+	self.retry = &policy
+	return self
+}
+
+// Mux makes the command write its stdout and stderr to mux, as lines labelled with label.
+func (self *Cmd) Mux(mux *Mux, label string) *Cmd {
+	self.stdout, self.stderr = mux.Writer(label), mux.Writer(label)
+	return self
+}
+
+// Host sets the label the command is logged with, like the name of the machine it affects.
+func (self *Cmd) Host(host string) *Cmd {
+	self.host = host
+	return self
+}
+
+// Logger makes the command log to l, or not at all if l is nil.
+func (self *Cmd) Logger(l Logger) *Cmd {
+	self.logger = l
+	return self
+}
+
+// Redact adds redactions to those in Redactions, to hide secrets in the arguments of the command when it is logged or fails.
+func (self *Cmd) Redact(redactions ...Redaction) *Cmd {
+	self.redactions = append(self.redactions, redactions...)
+	return self
+}
+
+// label returns the host label of the command.
+func (self *Cmd) label() string {
+	if self.host == "" {
+		return Host
+	}
+	return self.host
+}
+
+// log logs the command with its host label, followed by suffix.
+func (self *Cmd) log(suffix string) {
+	if self.logger != nil {
+		self.logger.Infof(" ( *** %v ) %v%v", self.label(), self, suffix)
+	}
+}
+
+// String returns the command line, quoted with Quote so that it can be pasted into a shell, with the secrets in its arguments
+// redacted.
+func (self *Cmd) String() string {
+	args := self.args
+	for _, redaction := range Redactions {
+		args = redaction(args)
+	}
+	for _, redaction := range self.redactions {
+		args = redaction(args)
+	}
+	return QuoteAll(append([]string{self.path}, args...)...)
+}
+
+/*
+Start logs and starts the command, and returns a channel that receives the result of it, nil or an *Error. If the context is
+done, or the timeout passes, before the command exits, the Cause of the Error is the error of the context, context.Canceled
+or context.DeadlineExceeded.
+*/
+func (self *Cmd) Start() (result chan error) {
+	self.log("")
+	if self.retry == nil {
+		return self.startQuietly()
+	}
+	result = make(chan error, 1)
+	go func() {
+		result <- self.runRetried()
+	}()
+	return
+}
+
+// resetter is implemented by writers, like bytes.Buffer, that can forget what was written to them.
+type resetter interface {
+	Reset()
+}
+
+// runRetried runs the command until it succeeds, or the Retry gives up, and returns nil or a *RetryError.
+func (self *Cmd) runRetried() error {
+	attempts := multierror.MultiError{}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			for _, w := range []io.Writer{self.stdout, self.stderr} {
+				if r, ok := w.(resetter); ok {
+					r.Reset()
+				}
+			}
+		}
+		err := <-self.startQuietly()
+		if err == nil {
+			return nil
+		}
+		attempts = append(attempts, err)
+		delay, again := self.retry.Next(attempt)
+		if !again || !self.retry.retries(err) || self.ctx.Err() != nil {
+			return &RetryError{Attempts: attempts}
+		}
+		if self.logger != nil {
+			self.logger.Infof(" ( *** %v ) retrying %v in %v after: %v", self.label(), self, delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-self.ctx.Done():
+			return &RetryError{Attempts: attempts}
+		}
+	}
+}
+
+// startQuietly starts the command like Start, without logging it.
+func (self *Cmd) startQuietly() (result chan error) {
+	result = make(chan error, 1)
+	stdout, stderr := &tail{size: TailSize}, &tail{size: TailSize}
+	started := self.start(stdout, stderr)
+	go func() {
+		err := <-started
+		for _, w := range []io.Writer{self.stdout, self.stderr} {
+			if f, ok := w.(flusher); ok {
+				f.Flush()
+			}
+		}
+		result <- self.check(err, stdout, stderr)
+	}()
+	return
+}
+
+// flusher is implemented by writers, like MuxWriter, that buffer what the command writes.
+type flusher interface {
+	Flush() error
+}
+
+// check returns the *Error for err, the result of running the command, given the tails of its output.
+func (self *Cmd) check(err error, stdout, stderr *tail) error {
+	code := exitCode(err)
+	if err == nil && self.stderrFatal && strings.TrimSpace(stderr.String()) != "" {
+		err = StderrError(stderr.String())
+	}
+	if err == nil {
+		return nil
+	}
+	return &Error{
+		Command:  self.String(),
+		ExitCode: code,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Cause:    err,
+	}
+}
+
+func (self *Cmd) start(stdout, stderr *tail) (result chan error) {
+	result = make(chan error, 1)
+
+	ctx, cancel := self.ctx, context.CancelFunc(func() {})
+	if self.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, self.timeout)
+	}
+	if err := ctx.Err(); err != nil {
+		cancel()
+		result <- err
+		return
+	}
+
+	executor := self.executor
+	if executor == nil {
+		executor = DefaultExecutor
+	}
+	process, err := executor.Start(Spec{
+		Path:        self.path,
+		Args:        self.args,
+		Dir:         self.dir,
+		Env:         self.env,
+		Stdin:       self.stdin,
+		Stdout:      tee(stdout, self.stdout),
+		Stderr:      tee(stderr, self.stderr),
+		Cancellable: ctx.Done() != nil,
+	})
+	if err != nil {
+		cancel()
+		result <- err
+		return
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- process.Wait()
+	}()
+	go func() {
+		defer cancel()
+		select {
+		case err := <-waited:
+			result <- err
+		case <-ctx.Done():
+			process.Terminate()
+			select {
+			case <-waited:
+			case <-time.After(self.grace):
+				process.Kill()
+				<-waited
+			}
+			result <- ctx.Err()
+		}
+	}()
+
+	return
+}
+
+// tee returns a writer writing to both t and w, if w isn't nil.
+func tee(t *tail, w io.Writer) io.Writer {
+	if w == nil {
+		return t
+	}
+	return io.MultiWriter(t, w)
+}
+
+// Run runs the command and returns its error.
+func (self *Cmd) Run() error {
+	return <-self.Start()
+}
+
+// Output runs the command and returns what it wrote to stdout and stderr, instead of writing it anywhere else.
+func (self *Cmd) Output() (stdout, stderr string, err error) {
+	o, e := &bytes.Buffer{}, &bytes.Buffer{}
+	self.stdout, self.stderr = o, e
+	err = self.Run()
+	stdout, stderr = o.String(), e.String()
+	return
+}
@@ -0,0 +1,81 @@
+package run
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runTestCmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stdout, _, err := Command("sh", "-c", "pwd; echo $RUN_TEST; cat").Dir(dir).Env("RUN_TEST=env").Stdin(strings.NewReader("stdin")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := dir + "\nenv\nstdin"; stdout != want {
+		t.Errorf("Wanted %#v, got %#v", want, stdout)
+	}
+
+	start := time.Now()
+	if err = Command("sleep", "10").Timeout(50 * time.Millisecond).Run(); cause(err) != context.DeadlineExceeded {
+		t.Errorf("Wanted %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Wanted the timeout to stop sleep at once, it took %v", elapsed)
+	}
+
+	start = time.Now()
+	if err = Command("sh", "-c", "trap '' TERM; sleep 10").Timeout(50 * time.Millisecond).Grace(100 * time.Millisecond).Run(); cause(err) != context.DeadlineExceeded {
+		t.Errorf("Wanted %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Wanted SIGKILL to stop the command ignoring SIGTERM, it took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := Command("sleep", "10").Context(ctx).Start()
+	cancel()
+	if err = <-done; cause(err) != context.Canceled {
+		t.Errorf("Wanted %v, got %v", context.Canceled, err)
+	}
+	if err = Command("true").Context(ctx).Run(); cause(err) != context.Canceled {
+		t.Errorf("Wanted %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestError(t *testing.T) {
+	if err := Command("sh", "-c", "echo warning >&2").Stderr(nil).Run(); err != nil {
+		t.Errorf("Wanted no error for a successful command writing to stderr, got %v", err)
+	}
+	err := Command("sh", "-c", "echo warning >&2").Stderr(nil).StderrFatal().Run()
+	if runErr, ok := err.(*Error); !ok || runErr.ExitCode != 0 || runErr.Cause != StderrError("warning\n") {
+		t.Errorf("Wanted a StderrError, got %#v", err)
+	}
+
+	TailSize = 4
+	defer func() {
+		TailSize = 4096
+	}()
+	err = Command("sh", "-c", "echo output; echo failure >&2; exit 3").Stdout(nil).Stderr(nil).Run()
+	runErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Wanted an *Error, got %#v", err)
+	}
+	if want := (Error{Command: `sh -c 'echo output; echo failure >&2; exit 3'`, ExitCode: 3, Stdout: "put\n", Stderr: "ure\n"}); runErr.Command != want.Command || runErr.ExitCode != want.ExitCode || runErr.Stdout != want.Stdout || runErr.Stderr != want.Stderr {
+		t.Errorf("Wanted %+v, got %+v", want, runErr)
+	}
+	if ExitCode(err) != 3 || ExitCode(nil) != -1 {
+		t.Errorf("Wanted 3 and -1, got %v and %v", ExitCode(err), ExitCode(nil))
+	}
+	if want := `sh -c 'echo output; echo failure >&2; exit 3' exited with status 3: ure`; err.Error() != want {
+		t.Errorf("Wanted %#v, got %#v", want, err.Error())
+	}
+}
@@ -0,0 +1,102 @@
+package run
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// TailSize is the number of bytes of the end of stdout and stderr that Error keeps.
+var TailSize = 4096
+
+/*
+Error is returned when a command fails: when it exits with a non zero status, can't be started, is cancelled, or writes to
+stderr when it runs with StderrFatal.
+*/
+type Error struct {
+	// Command is the command line that failed.
+	Command string
+	// ExitCode is the exit status of the command, or -1 if it didn't exit by itself.
+	ExitCode int
+	// Stdout and Stderr are the last TailSize bytes the command wrote to them.
+	Stdout string
+	Stderr string
+	// Cause is the error from running the command, like an *exec.ExitError, context.DeadlineExceeded, or a StderrError.
+	Cause error
+}
+
+func (self *Error) Error() string {
+	msg := ""
+	_, stderrFatal := self.Cause.(StderrError)
+	switch {
+	case self.ExitCode > 0:
+		msg = fmt.Sprintf("%v exited with status %v", self.Command, self.ExitCode)
+	case stderrFatal:
+		msg = fmt.Sprintf("%v wrote to stderr", self.Command)
+	default:
+		msg = fmt.Sprintf("%v failed: %v", self.Command, self.Cause)
+	}
+	if stderr := strings.TrimSpace(self.Stderr); stderr != "" {
+		msg = fmt.Sprintf("%v: %v", msg, stderr)
+	}
+	return msg
+}
+
+/*
+ExitCode returns the exit status of the command that caused err, or -1 if err isn't an *Error for a command that exited by
+itself. For a *RetryError it is the exit status of the last attempt.
+*/
+func ExitCode(err error) int {
+	if retryErr, ok := err.(*RetryError); ok {
+		err = retryErr.Last()
+	}
+	if runErr, ok := err.(*Error); ok {
+		return runErr.ExitCode
+	}
+	return -1
+}
+
+// exitCode returns the exit status in err, as returned by Process.Wait, or -1 if the process didn't exit by itself.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if status, ok := err.(ExitStatus); ok {
+		return int(status)
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Exited() {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
+
+/*
+StderrError is the Cause of the Error returned when a command running with StderrFatal writes to stderr, but exits
+successfully.
+*/
+type StderrError string
+
+func (self StderrError) Error() string {
+	return string(self)
+}
+
+// tail is an io.Writer that keeps the last size bytes written to it.
+type tail struct {
+	size int
+	buf  []byte
+}
+
+func (self *tail) Write(b []byte) (int, error) {
+	self.buf = append(self.buf, b...)
+	if over := len(self.buf) - self.size; over > 0 {
+		self.buf = append(self.buf[:0], self.buf[over:]...)
+	}
+	return len(b), nil
+}
+
+func (self *tail) String() string {
+	return string(self.buf)
+}
@@ -0,0 +1,98 @@
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+/*
+Executor starts the processes of commands. The default, DefaultExecutor, starts real processes, and runtest.Fake lets tests
+script what commands do instead.
+*/
+type Executor interface {
+	Start(spec Spec) (Process, error)
+}
+
+/*
+Spec describes a process to start.
+*/
+type Spec struct {
+	Path string
+	Args []string
+	Dir  string
+	// Env is added to the environment of the current process, which the process gets.
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Cancellable is set when the process may be terminated, and should then get its own process group.
+	Cancellable bool
+}
+
+/*
+Process is a started process.
+*/
+type Process interface {
+	// Wait waits for the process to exit, and returns nil, an ExitStatus or an *exec.ExitError if it failed.
+	Wait() error
+	// Terminate asks the process, and the processes it started, to exit.
+	Terminate()
+	// Kill makes the process, and the processes it started, exit.
+	Kill()
+}
+
+/*
+ExitStatus is an error that Processes not started by the os, like fakes, can return from Wait when they exit with a non zero
+status.
+*/
+type ExitStatus int
+
+func (self ExitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(self))
+}
+
+// DefaultExecutor is the Executor of commands that don't set their own with Cmd.Executor.
+var DefaultExecutor Executor = osExecutor{}
+
+// osExecutor starts real processes.
+type osExecutor struct{}
+
+func (osExecutor) Start(spec Spec) (result Process, err error) {
+	cmd := exec.Command(spec.Path, spec.Args...)
+	cmd.Dir, cmd.Env = spec.Dir, environ(spec.Env)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = spec.Stdin, spec.Stdout, spec.Stderr
+	if spec.Cancellable {
+		setProcessGroup(cmd)
+	}
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	result = osProcess{cmd: cmd}
+	return
+}
+
+type osProcess struct {
+	cmd *exec.Cmd
+}
+
+func (self osProcess) Wait() error {
+	return self.cmd.Wait()
+}
+
+func (self osProcess) Terminate() {
+	terminate(self.cmd.Process)
+}
+
+func (self osProcess) Kill() {
+	kill(self.cmd.Process)
+}
+
+// environ returns the environment of the current process with env added, or nil if env is empty.
+func environ(env []string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
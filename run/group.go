@@ -0,0 +1,93 @@
+package run
+
+import (
+	"context"
+	"sync"
+
+	"github.com/soundtrackyourbrand/utils/multierror"
+)
+
+/*
+Group runs commands concurrently, and stops them all when one of them fails:
+
+	group := run.NewGroup(ctx, &run.Mux{Color: true})
+	group.Start("api", run.Command("./api"))
+	group.Start("worker", run.Command("./worker"))
+	err := group.Wait()
+*/
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	mux    *Mux
+	wait   sync.WaitGroup
+	lock   sync.Mutex
+	errors multierror.MultiError
+	failed bool
+}
+
+/*
+NewGroup returns a Group whose commands stop when ctx is done. If mux isn't nil the output of the commands goes to it,
+labelled with their labels.
+*/
+func NewGroup(ctx context.Context, mux *Mux) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{
+		ctx:    ctx,
+		cancel: cancel,
+		mux:    mux,
+	}
+}
+
+/*
+Start starts cmd, replacing its context with the one of the group, and labels it with label.
+*/
+func (self *Group) Start(label string, cmd *Cmd) {
+	cmd.Context(self.ctx)
+	if cmd.host == "" {
+		cmd.Host(label)
+	}
+	if self.mux != nil {
+		cmd.Mux(self.mux, label)
+	}
+	self.wait.Add(1)
+	started := cmd.Start()
+	go func() {
+		defer self.wait.Done()
+		if err := <-started; err != nil {
+			self.lock.Lock()
+			defer self.lock.Unlock()
+			// commands stopped because another one failed didn't fail themselves
+			if !self.failed || cause(err) != context.Canceled {
+				self.errors = append(self.errors, err)
+			}
+			self.failed = true
+			self.cancel()
+		}
+	}()
+}
+
+/*
+Wait waits for all commands of the group, and returns a multierror.MultiError, which is a utils.MultiError, of those that
+failed, or nil if none did.
+*/
+func (self *Group) Wait() error {
+	self.wait.Wait()
+	self.cancel()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if len(self.errors) > 0 {
+		return self.errors
+	}
+	return nil
+}
+
+// cause returns the Cause of err if it is an *Error, or of the last attempt if it is a *RetryError, and otherwise err.
+func cause(err error) error {
+	if retryErr, ok := err.(*RetryError); ok {
+		err = retryErr.Last()
+	}
+	if runErr, ok := err.(*Error); ok {
+		return runErr.Cause
+	}
+	return err
+}
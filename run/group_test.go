@@ -0,0 +1,54 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/soundtrackyourbrand/utils/multierror"
+)
+
+func TestMux(t *testing.T) {
+	out, log := &bytes.Buffer{}, &bytes.Buffer{}
+	mux := &Mux{Out: out, Log: log, Color: true, TimeFormat: "15"}
+	w := mux.Writer("a")
+	w.Write([]byte("one\ntw"))
+	mux.Writer("b").Write([]byte("three\n"))
+	w.Write([]byte("o\nfour"))
+	w.Flush()
+	hour := time.Now().Format("15")
+	if want := "[a] " + hour + " one\n[b] " + hour + " three\n[a] " + hour + " two\n[a] " + hour + " four\n"; log.String() != want {
+		t.Errorf("Wanted %#v, got %#v", want, log.String())
+	}
+	if want := "\x1b[36m[a] " + hour + " \x1b[0mone\n\x1b[32m[b] " + hour + " \x1b[0mthree\n"; !bytes.HasPrefix(out.Bytes(), []byte(want)) {
+		t.Errorf("Wanted %#v to start with %#v", out.String(), want)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	out := &bytes.Buffer{}
+	group := NewGroup(context.Background(), &Mux{Out: out})
+	group.Start("ok", Command("sh", "-c", "echo fine").Logger(nil))
+	group.Start("fail", Command("sh", "-c", "sleep 0.1; printf failing; exit 4").Logger(nil))
+	group.Start("slow", Command("sleep", "10").Logger(nil))
+	start := time.Now()
+	err := group.Wait()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Wanted the failure to stop the slow command, it took %v", elapsed)
+	}
+	merr, ok := err.(multierror.MultiError)
+	if !ok || len(merr) != 1 || ExitCode(merr[0]) != 4 {
+		t.Fatalf("Wanted one error with exit code 4, got %#v", err)
+	}
+	if !regexp.MustCompile(`(?m)^\[ok\] \S+ fine$`).MatchString(out.String()) || !regexp.MustCompile(`(?m)^\[fail\] \S+ failing$`).MatchString(out.String()) {
+		t.Errorf("Wanted labelled lines, got %#v", out.String())
+	}
+
+	group = NewGroup(context.Background(), nil)
+	group.Start("ok", Command("true").Logger(nil))
+	if err = group.Wait(); err != nil {
+		t.Errorf("Wanted no error, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+/*
+Logger logs the commands run. It is a subset of httpcontext.Logger, so any of those can be used.
+*/
+type Logger interface {
+	Infof(format string, args ...interface{})
+}
+
+// writerLogger logs each message as a line in a writer.
+type writerLogger struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+/*
+NewLogger returns a Logger writing each message as a line to w.
+*/
+func NewLogger(w io.Writer) Logger {
+	return &writerLogger{writer: w}
+}
+
+func (self *writerLogger) Infof(format string, args ...interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	fmt.Fprintf(self.writer, strings.TrimSuffix(format, "\n")+"\n", args...)
+}
+
+// DefaultLogger is the Logger of commands that don't set their own with Cmd.Logger. It writes to stderr, to keep stdout for the output of programs.
+var DefaultLogger = NewLogger(os.Stderr)
+
+/*
+Host is the label of commands that don't set their own with Cmd.Host.
+
+Deprecated: Use Cmd.Host, which doesn't affect other commands running at the same time.
+*/
+var Host = "LOCAL"
+
+/*
+Redaction replaces the secrets in the arguments of a command, before they are logged or put in errors.
+*/
+type Redaction func(args []string) []string
+
+// redacted replaces secrets.
+const redacted = "***"
+
+/*
+RedactFlags returns a Redaction that hides the values of the flags named, both as separate arguments, like "--password x",
+and in the same argument, like "--password=x".
+*/
+func RedactFlags(names ...string) Redaction {
+	return func(args []string) (result []string) {
+		result = append([]string{}, args...)
+		for i, arg := range result {
+			for _, name := range names {
+				if arg == name && i+1 < len(result) {
+					result[i+1] = redacted
+				} else if strings.HasPrefix(arg, name+"=") {
+					result[i] = name + "=" + redacted
+				}
+			}
+		}
+		return
+	}
+}
+
+/*
+RedactPattern returns a Redaction that hides the first submatch, or the entire match if it has no submatches, of the regular
+expression pattern in each argument.
+*/
+func RedactPattern(pattern string) Redaction {
+	re := regexp.MustCompile(pattern)
+	return func(args []string) (result []string) {
+		for _, arg := range args {
+			matches := re.FindAllStringSubmatchIndex(arg, -1)
+			// replace from the end, to keep the indices of earlier matches valid
+			for i := len(matches) - 1; i >= 0; i-- {
+				start, end := matches[i][0], matches[i][1]
+				if len(matches[i]) > 2 && matches[i][2] >= 0 {
+					start, end = matches[i][2], matches[i][3]
+				}
+				arg = arg[:start] + redacted + arg[end:]
+			}
+			result = append(result, arg)
+		}
+		return
+	}
+}
+
+/*
+RedactValues returns a Redaction that hides the secrets wherever they appear.
+*/
+func RedactValues(secrets ...string) Redaction {
+	return func(args []string) (result []string) {
+		for _, arg := range args {
+			for _, secret := range secrets {
+				if secret != "" {
+					arg = strings.Replace(arg, secret, redacted, -1)
+				}
+			}
+			result = append(result, arg)
+		}
+		return
+	}
+}
+
+/*
+Redactions apply to all commands, before those added with Cmd.Redact. By default they hide the values of common password and
+token flags, and the passwords in URLs.
+*/
+var Redactions = []Redaction{
+	RedactFlags("--password", "--passwd", "--token", "--secret", "--api-key"),
+	RedactPattern(`://[^/@:]*:([^/@]*)@`),
+}
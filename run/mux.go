@@ -0,0 +1,113 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// colors are the ANSI colors Mux gives labels, in order.
+var colors = []int{36, 32, 33, 35, 34, 31}
+
+/*
+Mux multiplexes the output of concurrent commands, line by line, prefixing each line with the label of the command and the
+time it was written:
+
+	mux := &run.Mux{Color: true}
+	run.Command("tail", "-f", "app.log").Mux(mux, "app").Start()
+
+Partial lines are buffered until they are finished, or the command exits, so that lines of different commands never mix.
+*/
+type Mux struct {
+	// Out gets the labelled lines. Nil means os.Stdout.
+	Out io.Writer
+	// Log, if not nil, also gets the labelled lines, without colors, like a log file.
+	Log io.Writer
+	// Color makes the labels in Out colored, with a different color for each label.
+	Color bool
+	// TimeFormat is the format of the times of lines. Empty means "15:04:05.000".
+	TimeFormat string
+
+	lock   sync.Mutex
+	colors map[string]int
+}
+
+/*
+Writer returns a writer that writes what is written to it as lines labelled with label, see Cmd.Mux. Flush writes the
+partial line left, if any.
+*/
+func (self *Mux) Writer(label string) *MuxWriter {
+	return &MuxWriter{
+		mux:   self,
+		label: label,
+	}
+}
+
+func (self *Mux) writeLine(label string, line []byte) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	format := self.TimeFormat
+	if format == "" {
+		format = "15:04:05.000"
+	}
+	prefix := fmt.Sprintf("[%v] %v ", label, time.Now().Format(format))
+	out := self.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	if self.Color {
+		if self.colors == nil {
+			self.colors = map[string]int{}
+		}
+		color, found := self.colors[label]
+		if !found {
+			color = colors[len(self.colors)%len(colors)]
+			self.colors[label] = color
+		}
+		fmt.Fprintf(out, "\x1b[%dm%v\x1b[0m%s\n", color, prefix, line)
+	} else {
+		fmt.Fprintf(out, "%v%s\n", prefix, line)
+	}
+	if self.Log != nil {
+		fmt.Fprintf(self.Log, "%v%s\n", prefix, line)
+	}
+}
+
+/*
+MuxWriter is a line buffered writer of a Mux.
+*/
+type MuxWriter struct {
+	mux   *Mux
+	label string
+	lock  sync.Mutex
+	buf   []byte
+}
+
+func (self *MuxWriter) Write(b []byte) (int, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.buf = append(self.buf, b...)
+	for {
+		i := bytes.IndexByte(self.buf, '\n')
+		if i == -1 {
+			break
+		}
+		self.mux.writeLine(self.label, bytes.TrimSuffix(self.buf[:i], []byte("\r")))
+		self.buf = self.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes the partial line left, if any.
+func (self *MuxWriter) Flush() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if len(self.buf) > 0 {
+		self.mux.writeLine(self.label, self.buf)
+		self.buf = nil
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package run
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+/*
+Pipeline runs commands connected by pipes, like a shell does, but without a shell to quote the arguments for:
+
+	err := run.Pipe(run.Command("git", "log", "--oneline"), run.Command("head", "-n", "5")).Run()
+
+The first command reads the stdin of the pipeline, and the last command writes its stdout. The stderr of each command goes
+where the command was told to write it. Pipelines are logged with the Logger and host label of the first command.
+*/
+type Pipeline struct {
+	cmds []*Cmd
+}
+
+// Pipe returns a Pipeline connecting the stdout of each of cmds to the stdin of the next one.
+func Pipe(cmds ...*Cmd) *Pipeline {
+	return &Pipeline{
+		cmds: cmds,
+	}
+}
+
+// String returns the command lines of the pipeline separated by " | ", with the secrets in their arguments redacted.
+func (self *Pipeline) String() string {
+	lines := make([]string, len(self.cmds))
+	for i, cmd := range self.cmds {
+		lines[i] = cmd.String()
+	}
+	return strings.Join(lines, " | ")
+}
+
+/*
+Start logs and starts the pipeline, and returns a channel that receives the result of it.
+
+Like with pipefail in bash, the result is the error of the first command that failed, or nil if none did. Commands failing
+after the command reading their output has exited, usually killed by SIGPIPE, don't fail the pipeline.
+*/
+func (self *Pipeline) Start() (result chan error) {
+	result = make(chan error, 1)
+	if len(self.cmds) == 0 {
+		result <- nil
+		return
+	}
+	first := self.cmds[0]
+	if first.logger != nil {
+		first.logger.Infof(" ( *** %v ) %v", first.label(), self)
+	}
+
+	readers := make([]*io.PipeReader, len(self.cmds))
+	writers := make([]*io.PipeWriter, len(self.cmds))
+	for i := 0; i+1 < len(self.cmds); i++ {
+		readers[i+1], writers[i] = io.Pipe()
+		self.cmds[i].Stdout(writers[i])
+		self.cmds[i+1].Stdin(readers[i+1])
+	}
+
+	lock := &sync.Mutex{}
+	done := make([]bool, len(self.cmds))
+	errs := make([]error, len(self.cmds))
+	wait := &sync.WaitGroup{}
+	for i, cmd := range self.cmds {
+		i, started := i, cmd.startQuietly()
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			err := <-started
+			lock.Lock()
+			done[i] = true
+			if i+1 < len(self.cmds) && done[i+1] {
+				err = nil
+			}
+			errs[i] = err
+			lock.Unlock()
+			if writers[i] != nil {
+				writers[i].Close()
+			}
+			if readers[i] != nil {
+				readers[i].Close()
+			}
+		}()
+	}
+	go func() {
+		wait.Wait()
+		for _, err := range errs {
+			if err != nil {
+				result <- err
+				return
+			}
+		}
+		result <- nil
+	}()
+	return
+}
+
+// Run runs the pipeline and returns its error.
+func (self *Pipeline) Run() error {
+	return <-self.Start()
+}
+
+// Output runs the pipeline and returns what its last command wrote to stdout, and what all commands wrote to stderr.
+func (self *Pipeline) Output() (stdout, stderr string, err error) {
+	o, e := &bytes.Buffer{}, &lockedBuffer{}
+	if len(self.cmds) > 0 {
+		self.cmds[len(self.cmds)-1].Stdout(o)
+	}
+	for _, cmd := range self.cmds {
+		cmd.Stderr(e)
+	}
+	err = self.Run()
+	stdout, stderr = o.String(), e.buf.String()
+	return
+}
+
+// lockedBuffer lets several commands write to the same buffer.
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (self *lockedBuffer) Write(b []byte) (int, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.buf.Write(b)
+}
+
+/*
+Quote returns s quoted for POSIX shells, like sh and bash, for the cases where a command must be run by a shell, like remote
+commands run over ssh. Strings of only safe characters are returned as they are.
+*/
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%_+=:,./-", c) {
+			return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+		}
+	}
+	return s
+}
+
+// QuoteAll returns the args quoted with Quote, separated by spaces.
+func QuoteAll(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,55 @@
+package run
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	stdout, stderr, err := Pipe(
+		Command("sh", "-c", "printf 'a b\\nc\\nb d\\n'; echo warning >&2").Logger(nil),
+		Command("grep", "b").Logger(nil),
+		Command("wc", "-l").Logger(nil),
+	).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(stdout) != "2" || stderr != "warning\n" {
+		t.Errorf("Wanted 2 and a warning, got %#v and %#v", stdout, stderr)
+	}
+
+	if err = Pipe(Command("yes").Logger(nil), Command("head", "-n", "1").Stdout(nil).Logger(nil)).Run(); err != nil {
+		t.Errorf("Wanted yes cut off by head to not fail the pipeline, got %v", err)
+	}
+
+	err = Pipe(Command("sh", "-c", "exit 3").Logger(nil), Command("cat").Stdout(nil).Logger(nil)).Run()
+	if ExitCode(err) != 3 {
+		t.Errorf("Wanted exit code 3, got %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = Pipe(Command("echo", "a b").Logger(NewLogger(buf)), Command("cat").Stdout(nil)).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := " ( *** LOCAL ) echo 'a b' | cat\n"; buf.String() != want {
+		t.Errorf("Wanted %#v, got %#v", want, buf.String())
+	}
+}
+
+func TestQuote(t *testing.T) {
+	args := []string{"", "plain-arg_1.go", "with space", "it's", `"$HOME" \n`, "a\nb", "*"}
+	want := `'' plain-arg_1.go 'with space' 'it'\''s' '"$HOME" \n' 'a
+b' '*'`
+	if got := QuoteAll(args...); got != want {
+		t.Errorf("Wanted %#v, got %#v", want, got)
+	}
+	out, err := exec.Command("sh", "-c", "for a in "+QuoteAll(args...)+`; do printf '%s|' "$a"; done`).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(args, "|") + "|"; string(out) != want {
+		t.Errorf("Wanted %#v, got %#v", want, string(out))
+	}
+}
@@ -0,0 +1,73 @@
+package run
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/soundtrackyourbrand/utils/multierror"
+	"github.com/soundtrackyourbrand/utils/retry"
+)
+
+/*
+Retry is a policy for retrying failed commands, see Cmd.Retry.
+
+Commands are run at most MaxAttempts times, with the delays of Backoff between the attempts, of the embedded retry.Policy.
+They are retried if they exit with one of ExitCodes, or write something matching one of StderrPatterns to stderr. If neither
+are given, all failures, including timeouts, are retried. Commands whose context is done are never retried.
+
+Before each retry, the stdout and stderr of the command are reset, if they can be, like a bytes.Buffer, so that they only
+contain the output of the last attempt. Stdin is not read again, so commands reading stdin should only be retried if the
+attempts fail before reading it.
+*/
+type Retry struct {
+	retry.Policy
+	ExitCodes      []int
+	StderrPatterns []*regexp.Regexp
+}
+
+/*
+DefaultRetry retries all failures with retry.DefaultPolicy, like client.DoRequest.
+*/
+var DefaultRetry = Retry{
+	Policy: retry.DefaultPolicy,
+}
+
+// retries returns whether err, the result of an attempt, should be retried.
+func (self Retry) retries(err error) bool {
+	runErr, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	if len(self.ExitCodes) == 0 && len(self.StderrPatterns) == 0 {
+		return true
+	}
+	for _, code := range self.ExitCodes {
+		if runErr.ExitCode == code {
+			return true
+		}
+	}
+	for _, pattern := range self.StderrPatterns {
+		if pattern.MatchString(runErr.Stderr) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+RetryError is returned when all attempts to run a retried command failed, or the last attempt failed in a way the Retry
+doesn't retry.
+*/
+type RetryError struct {
+	// Attempts are the errors of each attempt, in order.
+	Attempts multierror.MultiError
+}
+
+// Last returns the error of the last attempt.
+func (self *RetryError) Last() error {
+	return self.Attempts[len(self.Attempts)-1]
+}
+
+func (self *RetryError) Error() string {
+	return fmt.Sprintf("failed %v attempts: %v", len(self.Attempts), self.Attempts)
+}
@@ -0,0 +1,64 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/soundtrackyourbrand/utils/retry"
+)
+
+func TestRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runTestRetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// flaky fails with the attempt number as exit status, until the third attempt
+	flaky := func(name string) *Cmd {
+		counter := filepath.Join(dir, name)
+		script := `n=$(($(cat "$0" 2>/dev/null || echo 0) + 1)); echo $n > "$0"; echo "attempt $n"; echo "error $n" >&2; [ $n -ge 3 ] || exit $n`
+		return Command("sh", "-c", script, counter).Logger(nil)
+	}
+	policy := Retry{Policy: retry.Policy{MaxAttempts: 5, Backoff: retry.Backoff{Initial: time.Millisecond}}}
+
+	stdout, _, err := flaky("all").Retry(policy).Output()
+	if err != nil || stdout != "attempt 3\n" {
+		t.Errorf("Wanted the output of the third attempt, got %#v, %v", stdout, err)
+	}
+
+	policy.MaxAttempts = 2
+	err = flaky("max").Retry(policy).Stdout(nil).Stderr(nil).Run()
+	retryErr, ok := err.(*RetryError)
+	if !ok || len(retryErr.Attempts) != 2 || ExitCode(retryErr.Attempts[0]) != 1 || ExitCode(err) != 2 {
+		t.Fatalf("Wanted 2 attempts exiting with 1 and 2, got %#v", err)
+	}
+
+	policy.MaxAttempts = 5
+	policy.ExitCodes = []int{1}
+	if err = flaky("codes").Retry(policy).Stdout(nil).Stderr(nil).Run(); ExitCode(err) != 2 || len(err.(*RetryError).Attempts) != 2 {
+		t.Errorf("Wanted exit code 2 to stop the retries, got %v", err)
+	}
+	policy.ExitCodes = nil
+	policy.StderrPatterns = []*regexp.Regexp{regexp.MustCompile(`error [12]`)}
+	if err = flaky("patterns").Retry(policy).Stdout(nil).Stderr(nil).Run(); err != nil {
+		t.Errorf("Wanted stderr to make it retry until it succeeds, got %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	flaky("log").Retry(policy).Logger(NewLogger(buf)).Stdout(nil).Stderr(nil).Run()
+	if want := regexp.MustCompile(`(?m)^ \( \*\*\* LOCAL \) retrying sh .* in 1ms after: sh .* exited with status 1: error 1$`); !want.MatchString(buf.String()) {
+		t.Errorf("Wanted a log of the retry, got %#v", buf.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = Command("true").Logger(nil).Context(ctx).Retry(policy).Run(); len(err.(*RetryError).Attempts) != 1 {
+		t.Errorf("Wanted a cancelled command to not be retried, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+runtest contains a fake run.Executor, to test code running commands without running them:
+
+	fake := &runtest.Fake{}
+	fake.Expect("git", "rev-parse", "HEAD").Stdout("abc123\n")
+	defer fake.Install()()
+	rev, err := utils.GitRevision(".")
+	if err := fake.Check(); err != nil {
+		t.Fatal(err)
+	}
+*/
+package runtest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/soundtrackyourbrand/utils/run"
+)
+
+/*
+Invocation is a command the Fake was asked to run.
+*/
+type Invocation struct {
+	Path string
+	Args []string
+	Dir  string
+	// Env is the environment added to the one of the current process.
+	Env []string
+	// Stdin is what the command got as stdin, unless it got a file, like os.Stdin, which is left unread.
+	Stdin string
+}
+
+// Line returns the command line of the invocation, quoted like a shell would need it.
+func (self Invocation) Line() string {
+	return run.QuoteAll(append([]string{self.Path}, self.Args...)...)
+}
+
+/*
+Expectation is a command the Fake expects to run, and what it does when it runs.
+*/
+type Expectation struct {
+	line    string
+	pattern *regexp.Regexp
+	stdout  string
+	stderr  string
+	exit    int
+	times   int
+	used    int
+}
+
+// Stdout makes the command write s to stdout.
+func (self *Expectation) Stdout(s string) *Expectation {
+	self.stdout = s
+	return self
+}
+
+// Stderr makes the command write s to stderr.
+func (self *Expectation) Stderr(s string) *Expectation {
+	self.stderr = s
+	return self
+}
+
+// Exit makes the command exit with status code.
+func (self *Expectation) Exit(code int) *Expectation {
+	self.exit = code
+	return self
+}
+
+// Times makes the command expected n times instead of once. Zero means any number of times, including none.
+func (self *Expectation) Times(n int) *Expectation {
+	self.times = n
+	return self
+}
+
+func (self *Expectation) matches(line string) bool {
+	if self.times > 0 && self.used >= self.times {
+		return false
+	}
+	if self.pattern != nil {
+		return self.pattern.MatchString(line)
+	}
+	return self.line == line
+}
+
+func (self *Expectation) String() string {
+	if self.pattern != nil {
+		return self.pattern.String()
+	}
+	return self.line
+}
+
+/*
+Fake is a run.Executor that runs the commands it expects by doing what the Expectations say, records all commands it is
+asked to run, and fails those it doesn't expect.
+*/
+type Fake struct {
+	lock         sync.Mutex
+	expectations []*Expectation
+	invocations  []Invocation
+	unexpected   []string
+}
+
+// Expect makes the Fake expect path to be run with exactly args.
+func (self *Fake) Expect(path string, args ...string) *Expectation {
+	return self.expect(&Expectation{
+		line: Invocation{Path: path, Args: args}.Line(),
+	})
+}
+
+// ExpectPattern makes the Fake expect a command whose Line matches the regular expression pattern.
+func (self *Fake) ExpectPattern(pattern string) *Expectation {
+	return self.expect(&Expectation{
+		pattern: regexp.MustCompile(pattern),
+	})
+}
+
+func (self *Fake) expect(expectation *Expectation) *Expectation {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	expectation.times = 1
+	self.expectations = append(self.expectations, expectation)
+	return expectation
+}
+
+// Install makes the Fake the run.DefaultExecutor, and returns a func restoring the previous one.
+func (self *Fake) Install() (restore func()) {
+	previous := run.DefaultExecutor
+	run.DefaultExecutor = self
+	return func() {
+		run.DefaultExecutor = previous
+	}
+}
+
+// Invocations returns the commands the Fake was asked to run, in order.
+func (self *Fake) Invocations() []Invocation {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return append([]Invocation{}, self.invocations...)
+}
+
+// Check returns an error describing the commands that ran without being expected, and those expected that didn't run.
+func (self *Fake) Check() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	problems := []string{}
+	for _, line := range self.unexpected {
+		problems = append(problems, fmt.Sprintf("unexpected command %v", line))
+	}
+	for _, expectation := range self.expectations {
+		if expectation.used < expectation.times {
+			problems = append(problems, fmt.Sprintf("expected command %v to run %v times, it ran %v times", expectation, expectation.times, expectation.used))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%v", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func (self *Fake) Start(spec run.Spec) (result run.Process, err error) {
+	invocation := Invocation{
+		Path: spec.Path,
+		Args: spec.Args,
+		Dir:  spec.Dir,
+		Env:  spec.Env,
+	}
+	if _, isFile := spec.Stdin.(*os.File); spec.Stdin != nil && !isFile {
+		b, err := ioutil.ReadAll(spec.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		invocation.Stdin = string(b)
+	}
+	line := invocation.Line()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.invocations = append(self.invocations, invocation)
+	for _, expectation := range self.expectations {
+		if expectation.matches(line) {
+			expectation.used++
+			write(spec.Stdout, expectation.stdout)
+			write(spec.Stderr, expectation.stderr)
+			result = process{exit: expectation.exit}
+			return
+		}
+	}
+	self.unexpected = append(self.unexpected, line)
+	err = fmt.Errorf("runtest: unexpected command %v", line)
+	return
+}
+
+func write(w io.Writer, s string) {
+	if w != nil && s != "" {
+		io.WriteString(w, s)
+	}
+}
+
+// process is a run.Process that has already exited.
+type process struct {
+	exit int
+}
+
+func (self process) Wait() error {
+	if self.exit != 0 {
+		return run.ExitStatus(self.exit)
+	}
+	return nil
+}
+
+func (self process) Terminate() {}
+
+func (self process) Kill() {}
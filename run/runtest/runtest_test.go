@@ -0,0 +1,45 @@
+package runtest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soundtrackyourbrand/utils/run"
+)
+
+func TestFake(t *testing.T) {
+	fake := &Fake{}
+	fake.Expect("echo", "a b").Stdout("a b\n").Times(2)
+	fake.ExpectPattern(`^false`).Stderr("failed\n").Exit(3)
+
+	for i := 0; i < 2; i++ {
+		stdout, _, err := run.Command("echo", "a b").Executor(fake).Logger(nil).Output()
+		if err != nil || stdout != "a b\n" {
+			t.Errorf("Wanted a b, got %#v, %v", stdout, err)
+		}
+	}
+	_, stderr, err := run.Command("false", "x").Executor(fake).Logger(nil).Stdin(strings.NewReader("in")).Dir("d").Env("A=b").Output()
+	if run.ExitCode(err) != 3 || stderr != "failed\n" {
+		t.Errorf("Wanted exit code 3 and failed, got %v and %#v", err, stderr)
+	}
+	if err = fake.Check(); err != nil {
+		t.Error(err)
+	}
+
+	if err = run.Command("echo", "a b").Executor(fake).Logger(nil).Run(); err == nil {
+		t.Errorf("Wanted a third echo to be unexpected")
+	}
+	if err = fake.Check(); err == nil || !strings.Contains(err.Error(), `unexpected command echo 'a b'`) {
+		t.Errorf("Wanted an unexpected command, got %v", err)
+	}
+	fake.Expect("never")
+	if err = fake.Check(); err == nil || !strings.Contains(err.Error(), "expected command never to run 1 times, it ran 0 times") {
+		t.Errorf("Wanted a missing command, got %v", err)
+	}
+
+	want := Invocation{Path: "false", Args: []string{"x"}, Dir: "d", Env: []string{"A=b"}, Stdin: "in"}
+	if got := fake.Invocations(); len(got) != 4 || !reflect.DeepEqual(got[2], want) {
+		t.Errorf("Wanted %+v as the third of 4 invocations, got %+v", want, got)
+	}
+}
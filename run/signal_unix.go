@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes cmd lead a new process group, so that terminate and kill reach the processes it starts.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func terminate(p *os.Process) {
+	syscall.Kill(-p.Pid, syscall.SIGTERM)
+}
+
+func kill(p *os.Process) {
+	syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
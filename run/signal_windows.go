@@ -0,0 +1,19 @@
+package run
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing, since Windows has no process groups to signal.
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// terminate kills p, since Windows has no SIGTERM.
+func terminate(p *os.Process) {
+	p.Kill()
+}
+
+func kill(p *os.Process) {
+	p.Kill()
+}
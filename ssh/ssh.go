@@ -5,9 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
 	"github.com/soundtrackyourbrand/ssh"
 	utilsRun "github.com/soundtrackyourbrand/utils/run"
@@ -46,7 +44,7 @@ func Rsync(user string, key []byte, addr, src, dst string, excludes ...string) (
 	if err != nil {
 		return
 	}
-	if err = Run(creds, fmt.Sprintf("%v:22", addr), fmt.Sprintf("mkdir -p %#v", dst)); err != nil {
+	if err = Run(creds, fmt.Sprintf("%v:22", addr), "mkdir -p "+utilsRun.Quote(dst)); err != nil {
 		return
 	}
 	tmpdir, err := ioutil.TempDir("", "utilsSshRsync")
@@ -72,12 +70,14 @@ func Rsync(user string, key []byte, addr, src, dst string, excludes ...string) (
 	if err = os.Chmod(outfilename, 0400); err != nil {
 		return
 	}
-	params := []string{"rsync", fmt.Sprintf("%#v", fmt.Sprintf("--rsh=ssh -i %#v -o \"StrictHostKeyChecking no\"", outfilename)), "--progress", "-avzc", "-C", "--delete"}
+	// rsync splits the remote shell command itself, honouring quotes like a shell
+	rsh := utilsRun.QuoteAll("ssh", "-i", outfilename, "-o", "StrictHostKeyChecking no")
+	params := []string{"--rsh=" + rsh, "--progress", "-avzc", "-C", "--delete"}
 	for _, excl := range excludes {
 		params = append(params, "--exclude", excl)
 	}
-	params = append(params, fmt.Sprintf("%v", src), fmt.Sprintf("%v@%v:%v", user, addr, dst))
-	if err = utilsRun.Run("sh", "-c", strings.Join(params, " ")+" 2>&1"); err != nil {
+	params = append(params, src, fmt.Sprintf("%v@%v:%v", user, addr, dst))
+	if err = utilsRun.Run("rsync", params...); err != nil {
 		return
 	}
 	return
@@ -94,28 +94,21 @@ func TarCopy(creds Creds, addr, src, dst string, excludes ...string) (err error)
 		params = append(params, "--exclude", exclude)
 	}
 	params = append(params, "-c", "-z", "-C", filepath.Dir(src), filepath.Base(src))
-	tar := exec.Command("tar", params...)
-
 	pipein, pipeout := io.Pipe()
 	sess.Stdin, sess.Stdout, sess.Stderr = pipein, os.Stdout, os.Stderr
-	tar.Stdin, tar.Stdout, tar.Stderr = os.Stdin, pipeout, os.Stderr
+	tar := utilsRun.Command("tar", params...).Stdin(os.Stdin).Stdout(pipeout)
 
 	remoteDone := make(chan struct{})
 
 	go func() {
-		cmd := fmt.Sprintf("mkdir -p %#v && tar -x -v -z -C %#v", dst, dst)
-		fmt.Printf(" *** ( %v ) %#v\n", addr, cmd)
+		cmd := fmt.Sprintf("mkdir -p %v && tar -x -v -z -C %v", utilsRun.Quote(dst), utilsRun.Quote(dst))
+		utilsRun.DefaultLogger.Infof(" ( *** %v ) %v", addr, cmd)
 		if err := sess.Run(cmd); err != nil {
 			panic(err)
 		}
 		close(remoteDone)
 	}()
 
-	fmt.Printf(" ( *** %v ) %v", utilsRun.Host, "tar")
-	for _, bit := range params {
-		fmt.Printf(" %#v", bit)
-	}
-	fmt.Println("")
 	if err = tar.Run(); err != nil {
 		return
 	}
@@ -140,7 +133,7 @@ func Run(creds Creds, addr, cmd string) (err error) {
 	remoteDone := make(chan struct{})
 
 	go func() {
-		fmt.Printf(" *** ( %v ) %#v\n", addr, cmd)
+		utilsRun.DefaultLogger.Infof(" ( *** %v ) %v", addr, cmd)
 		if err = sess.Run(cmd); err != nil {
 			return
 		}
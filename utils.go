@@ -13,7 +13,6 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -30,6 +29,7 @@ import (
 
 	"net/http"
 
+	"github.com/soundtrackyourbrand/utils/multierror"
 	"github.com/soundtrackyourbrand/utils/run"
 	"github.com/go-errors/errors"
 )
@@ -362,9 +362,10 @@ var GitRevisionAt = time.Unix(0, {{.Time}})
 `))
 
 func GitCommitted(dir string) (result bool, err error) {
-	_, _, err = run.RunAndReturn("git", "diff-index", "--quiet", "HEAD", "--")
+	_, _, err = run.RunAndReturn("git", "--git-dir", filepath.Join(dir, ".git"), "--work-tree", dir, "diff-index", "--quiet", "HEAD", "--")
 	if err != nil {
-		if _, ok := err.(*exec.ExitError); ok {
+		// diff-index --quiet exits with 1 when there are changes
+		if run.ExitCode(err) == 1 {
 			err = nil
 		}
 		return
@@ -433,24 +434,20 @@ type Time struct {
 }
 
 func (self Time) MarshalJSON(args ...interface{}) ([]byte, error) {
-	if len(args) == 1 {
-		if s, ok := args[0].(string); ok && s == "bigquery" {
-			return json.Marshal(self.Time)
-		}
+	if json.OptionsFrom(args...).Context == "bigquery" {
+		return json.Marshal(self.Time)
 	}
 	return json.Marshal(self.Time.Format(ISO8601DateTimeFormat))
 }
 
 func (self *Time) UnmarshalJSON(b []byte, args ...interface{}) (err error) {
-	if len(args) == 1 {
-		if s, ok := args[0].(string); ok && s == "bigquery" {
-			t := time.Time{}
-			if err = json.Unmarshal(b, &t); err != nil {
-				return
-			}
-			self.Time = t
+	if json.OptionsFrom(args...).Context == "bigquery" {
+		t := time.Time{}
+		if err = json.Unmarshal(b, &t); err != nil {
 			return
 		}
+		self.Time = t
+		return
 	}
 	var s string
 	if err = json.Unmarshal(b, &s); err == nil {
@@ -683,15 +680,8 @@ func ParseFlags(i interface{}, defaultMap map[string]string) (err error) {
 	return
 }
 
-type MultiError []error
-
-func (self MultiError) Error() string {
-	s := make([]string, len(self))
-	for index, err := range self {
-		s[index] = err.Error()
-	}
-	return strings.Join(s, ", ")
-}
+// MultiError is declared in the multierror package, to let packages that utils imports, like run, use it.
+type MultiError = multierror.MultiError
 
 type Parallelizer struct {
 	count int64
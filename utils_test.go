@@ -5,6 +5,9 @@ import (
 	"math/big"
 	"math/rand"
 	"testing"
+
+	"github.com/soundtrackyourbrand/utils/run"
+	"github.com/soundtrackyourbrand/utils/run/runtest"
 )
 
 const (
@@ -66,3 +69,33 @@ func TestEncodeBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestGit(t *testing.T) {
+	fake := &runtest.Fake{}
+	defer fake.Install()()
+	logger := run.DefaultLogger
+	run.DefaultLogger = run.NewLogger(&bytes.Buffer{})
+	defer func() {
+		run.DefaultLogger = logger
+	}()
+
+	fake.Expect("git", "--git-dir", "repo/.git", "--work-tree", "repo", "rev-parse", "HEAD").Stdout("abc123\n")
+	fake.Expect("git", "--git-dir", "repo/.git", "--work-tree", "repo", "rev-parse", "--abbrev-ref", "HEAD").Stdout("master\n")
+	fake.Expect("git", "--git-dir", "repo/.git", "--work-tree", "repo", "diff-index", "--quiet", "HEAD", "--").Exit(1)
+	fake.ExpectPattern(`^git .* rev-parse HEAD$`).Stderr("fatal: not a git repository\n").Exit(128)
+	if rev, err := GitRevision("repo"); err != nil || rev != "abc123" {
+		t.Errorf("Wanted abc123, got %#v, %v", rev, err)
+	}
+	if branch, err := GitBranch("repo"); err != nil || branch != "master" {
+		t.Errorf("Wanted master, got %#v, %v", branch, err)
+	}
+	if committed, err := GitCommitted("repo"); err != nil || committed {
+		t.Errorf("Wanted uncommitted changes, got %v, %v", committed, err)
+	}
+	if _, err := GitRevision("elsewhere"); run.ExitCode(err) != 128 {
+		t.Errorf("Wanted exit code 128, got %v", err)
+	}
+	if err := fake.Check(); err != nil {
+		t.Error(err)
+	}
+}
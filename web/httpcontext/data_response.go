@@ -12,6 +12,8 @@ import (
 const (
 	ContentJSON       = "application/json; charset=UTF-8"
 	ContentJSONStream = "application/x-json-stream; charset=UTF-8"
+	ContentNDJSON     = "application/x-ndjson"
+	ContentJSONSeq    = "application/json-seq"
 	ContentExcelCSV   = "application/vnd.ms-excel"
 	ContentHTML       = "text/html"
 )
@@ -99,22 +101,27 @@ func (self DataResp) Render(c HTTPContextLogger) error {
 		}
 		return json.NewEncoder(c.Resp()).Encode(resp)
 
-	case ContentJSONStream:
+	case ContentJSONStream, ContentNDJSON, ContentJSONSeq:
+		format := json.NDJSON
+		if self.ContentType == ContentJSONSeq {
+			format = json.JSONSeq
+		}
+		writer := json.NewLineWriter(c.Resp(), format)
+		m := map[string]interface{}{}
 		for row := range self.Data {
-			m := map[string]interface{}{}
 			for k, v := range self.Headers {
 				m[v] = row[k]
 			}
-			err := json.NewEncoder(c.Resp()).Encode(m)
-			if err != nil {
+			if err := writer.Encode(m); err != nil {
 				return err
 			}
 		}
+		return nil
 	}
 	return fmt.Errorf("Unknown content type %#v", self.ContentType)
 }
 
-var suffixPattern = regexp.MustCompile("\\.(\\w{1,6})$")
+var suffixPattern = regexp.MustCompile("\\.(\\w{1,7})$")
 
 func DataHandle(c HTTPContextLogger, f func() (*DataResp, error), scopes ...string) {
 	Handle(c, func() (err error) {
@@ -134,6 +141,10 @@ func DataHandle(c HTTPContextLogger, f func() (*DataResp, error), scopes ...stri
 			resp.ContentType = ContentHTML
 		case "jjson":
 			resp.ContentType = ContentJSONStream
+		case "ndjson":
+			resp.ContentType = ContentNDJSON
+		case "jsonseq":
+			resp.ContentType = ContentJSONSeq
 		default:
 			resp.ContentType = ContentJSON
 		}
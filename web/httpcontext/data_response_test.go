@@ -0,0 +1,29 @@
+package httpcontext
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDataHandlerFunc(t *testing.T) {
+	handler := DataHandlerFunc(func(c HTTPContextLogger) (*DataResp, error) {
+		data := make(chan []interface{}, 2)
+		data <- []interface{}{"a", 1}
+		data <- []interface{}{"b", 2}
+		close(data)
+		return &DataResp{Data: data, Headers: []string{"name", "count"}}, nil
+	})
+	for path, want := range map[string]struct{ contentType, body string }{
+		"/report.ndjson":  {ContentNDJSON, "{\"count\":1,\"name\":\"a\"}\n{\"count\":2,\"name\":\"b\"}\n"},
+		"/report.jsonseq": {ContentJSONSeq, "\x1e{\"count\":1,\"name\":\"a\"}\n\x1e{\"count\":2,\"name\":\"b\"}\n"},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if got := w.Header().Get("Content-Type"); got != want.contentType {
+			t.Errorf("%v: wanted %v, got %v", path, want.contentType, got)
+		}
+		if got := w.Body.String(); got != want.body {
+			t.Errorf("%v: wanted %#v, got %#v", path, want.body, got)
+		}
+	}
+}
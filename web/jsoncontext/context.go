@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"time"
 
@@ -18,8 +20,10 @@ import (
 )
 
 const (
-	APIVersionHeader = "X-API-Version"
-	RespondMarshal   = "respond"
+	APIVersionHeader  = "X-API-Version"
+	RespondMarshal    = "respond"
+	ContentJSONPatch  = "application/json-patch+json"
+	ContentMergePatch = "application/merge-patch+json"
 )
 
 func APIVersionMatcher(minAPIVersion, maxAPIVersion int) mux.MatcherFunc {
@@ -48,6 +52,7 @@ type JSONContext interface {
 	DecodeJSON(i interface{}) error
 	DecodedBody() []byte
 	LoadJSON(i interface{}) error
+	PatchJSON(i interface{}) error
 	CopyJSON(in, out interface{}) error
 	MarshalJSON(subContext interface{}, body interface{}, reason interface{}) ([]byte, error)
 }
@@ -84,17 +89,40 @@ func (self *DefaultJSONContext) CopyJSON(in, out interface{}) (err error) {
 	if err != nil {
 		return
 	}
-	return json.CopyJSON(in, out, self.Req().Method, token.Scopes()...)
+	return forbidden(json.CopyJSON(in, out, self.Req().Method, token.Scopes()...), token.Scopes())
+}
+
+// forbidden turns *json.ScopeErrors into 403 errors.
+func forbidden(err error, scopes []string) error {
+	if scopeErr, ok := err.(*json.ScopeError); ok {
+		return NewError(403, scopeErr.Error(), fmt.Sprintf("Scopes: %+v", scopes), scopeErr)
+	}
+	return err
 }
 
 func (self *DefaultJSONContext) DecodedBody() []byte {
 	return self.decodedBody
 }
 
+/*
+JSONOptions returns the json.Options for JSON exchanged in this context: the context, and the API version and access
+token scopes of the request.
+*/
+func (self *DefaultJSONContext) JSONOptions(context string) (result json.Options) {
+	result = json.Options{
+		Context:    context,
+		APIVersion: self.apiVersion,
+	}
+	if at, err := self.AccessToken(nil); err == nil {
+		result.Scopes = at.Scopes()
+	}
+	return
+}
+
 func (self *DefaultJSONContext) DecodeJSON(i interface{}) error {
 	buf := &bytes.Buffer{}
 	bodyReader := io.TeeReader(self.Req().Body, buf)
-	err := json.NewDecoder(bodyReader).Decode(i)
+	err := json.NewDecoder(bodyReader).Decode(i, self.JSONOptions(self.Req().Method))
 	if err != nil {
 		return err
 	}
@@ -107,12 +135,36 @@ func (self *DefaultJSONContext) DecodeJSON(i interface{}) error {
 }
 
 func (self *DefaultJSONContext) LoadJSON(out interface{}) (err error) {
-	at, err := self.AccessToken(nil)
+	opts := self.JSONOptions(self.Req().Method)
+	return forbidden(json.LoadJSONOptions(self.Req().Body, out, opts), opts.Scopes)
+}
+
+/*
+PatchJSON will apply the request body to out as a JSON Patch if the Content-Type is application/json-patch+json, and as a
+JSON Merge Patch otherwise.
+
+Only fields with '<method>_scopes' tags, like 'PATCH_scopes', matching the scopes of the access token will be updated, and a
+patch touching any other field will cause a 403. Fields are named as in the API version of the request, like for LoadJSON.
+*/
+func (self *DefaultJSONContext) PatchJSON(out interface{}) (err error) {
+	body, err := ioutil.ReadAll(self.Req().Body)
 	if err != nil {
-		return json.LoadJSON(self.Req().Body, out, self.Req().Method)
+		return
+	}
+	self.decodedBody = body
+	opts := self.JSONOptions(self.Req().Method)
+	if strings.HasPrefix(self.Req().Header.Get("Content-Type"), ContentJSONPatch) {
+		err = json.ApplyPatchOptions(body, out, opts)
+	} else {
+		err = json.ApplyMergePatchOptions(body, out, opts)
 	}
-	scopes := at.Scopes()
-	return json.LoadJSON(self.Req().Body, out, self.Req().Method, scopes...)
+	if _, ok := err.(*json.ScopeError); ok {
+		return forbidden(err, opts.Scopes)
+	} else if err != nil {
+		mess := fmt.Sprintf("Unable to apply %#v as a patch: %v", string(body), err)
+		err = NewError(400, mess, mess, err)
+	}
+	return
 }
 
 func (self *DefaultJSONContext) APIVersion() int {
@@ -225,7 +277,8 @@ func (self *DefaultJSONContext) MarshalJSON(c interface{}, body interface{}, arg
 		reflect.ValueOf(&body).Elem().Set(reflect.MakeSlice(bodyVal.Type(), 0, 0))
 	}
 
-	if result, err = json.MarshalIndent(body, "", "  "); err != nil {
+	context, _ := arg.(string)
+	if result, err = json.MarshalIndent(body, "", "  ", self.JSONOptions(context)); err != nil {
 		return
 	}
 
@@ -3,6 +3,7 @@ package jsoncontext
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"reflect"
 	"regexp"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/soundtrackyourbrand/utils/json"
+	"github.com/soundtrackyourbrand/utils/json/schema"
 
 	"github.com/gorilla/mux"
 	"github.com/soundtrackyourbrand/utils"
@@ -29,6 +31,11 @@ var knownDocTags = map[reflect.Type]string{
 	reflect.TypeOf(time.Time{}):      "Time encoded like '2013-12-12T20:52:20.963842672+01:00'",
 }
 
+var knownJSONToDocTags = map[string]string{
+	json.JSONToBase64:     "Base64 encoded",
+	json.JSONToUnixMillis: "Milliseconds since the Unix epoch",
+}
+
 var DefaultDocTemplate *template.Template
 
 var DefaultDocTemplateContent = `
@@ -135,6 +142,22 @@ var DefaultEndpointTemplateContent = `
           <td>{{.Scopes}}</td>
         </tr>
       {{end}}
+			{{if .Versions}}
+			{{range .Versions}}
+			{{if .In}}
+			  <tr>
+				  <td valign="top">JSON request body, API version {{.MinAPIVersion}}{{if .MaxAPIVersion}}-{{.MaxAPIVersion}}{{else}}+{{end}}</td>
+					<td>{{RenderType .In}}</td>
+				</tr>
+			{{end}}
+			{{if .Out}}
+			  <tr>
+				  <td valign="top">JSON response body, API version {{.MinAPIVersion}}{{if .MaxAPIVersion}}-{{.MaxAPIVersion}}{{else}}+{{end}}</td>
+					<td>{{RenderType .Out}}</td>
+				</tr>
+			{{end}}
+			{{end}}
+			{{else}}
 			{{if .In}}
 			  <tr>
 				  <td valign="top">JSON request body</td>
@@ -147,6 +170,7 @@ var DefaultEndpointTemplateContent = `
 					<td>{{RenderType .Out}}</td>
 				</tr>
 			{{end}}
+			{{end}}
       </table>
     </div>
   </div>
@@ -218,15 +242,15 @@ type JSONType struct {
 	Comment     string
 }
 
-func newJSONType(in bool, t reflect.Type, filterOnScopes bool, scopeContexts []string, relevantScopes ...string) (result *JSONType) {
-	return newJSONTypeLoopProtector(nil, in, t, filterOnScopes, scopeContexts, relevantScopes...)
+func newJSONType(in bool, t reflect.Type, apiVersion int, filterOnScopes bool, scopeContexts []string, relevantScopes ...string) (result *JSONType) {
+	return newJSONTypeLoopProtector(nil, in, t, apiVersion, filterOnScopes, scopeContexts, relevantScopes...)
 }
 
 /*
 newJSONTypeLoopProtector is used by newJSONType to ensure that we don't cause eternal recursion when creating the structures necessary to render
 the type descriptions and examples
 */
-func newJSONTypeLoopProtector(seen []reflect.Type, in bool, t reflect.Type, filterOnScopes bool, scopeContexts []string, relevantScopes ...string) (result *JSONType) {
+func newJSONTypeLoopProtector(seen []reflect.Type, in bool, t reflect.Type, apiVersion int, filterOnScopes bool, scopeContexts []string, relevantScopes ...string) (result *JSONType) {
 	result = &JSONType{
 		In:          in,
 		ReflectType: t,
@@ -255,7 +279,7 @@ func newJSONTypeLoopProtector(seen []reflect.Type, in bool, t reflect.Type, filt
 			if field.Anonymous {
 				if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) {
 					// add the fields of anonymous struct fields flat into this JSONType
-					anonType := newJSONTypeLoopProtector(append(seen, t), in, field.Type, filterOnScopes, scopeContexts, relevantScopes...)
+					anonType := newJSONTypeLoopProtector(append(seen, t), in, field.Type, apiVersion, filterOnScopes, scopeContexts, relevantScopes...)
 					for name, typ := range anonType.Fields {
 						result.Fields[name] = typ
 					}
@@ -269,7 +293,7 @@ func newJSONTypeLoopProtector(seen []reflect.Type, in bool, t reflect.Type, filt
 				}
 			} else {
 				// and pick up their doc, name and encoding tags
-				jsonToTag := field.Tag.Get("jsonTo")
+				jsonTo, jsonToType := json.JSONTo(field)
 				jsonTag := field.Tag.Get("json")
 				docTag := field.Tag.Get("jsonDoc")
 				name := field.Name
@@ -280,6 +304,11 @@ func newJSONTypeLoopProtector(seen []reflect.Type, in bool, t reflect.Type, filt
 						parts := strings.Split(jsonTag, ",")
 						name = parts[0]
 					}
+					// fields not in the API version are skipped, and the others get their names in it
+					included := false
+					if name, included = json.Versions(field).Name(name, apiVersion); !included {
+						continue
+					}
 					for _, context := range scopeContexts {
 						updateScopesTag := field.Tag.Get(context + "_scopes")
 						if updateScopesTag != "" {
@@ -294,21 +323,24 @@ func newJSONTypeLoopProtector(seen []reflect.Type, in bool, t reflect.Type, filt
 					}
 					// fields without update scopes should never be displayed in the input type description
 					if !filterOnScopes || len(updateScopes) > 0 {
-						if jsonToTag == "" && knownEncodings[field.Type] != "" {
-							jsonToTag = knownEncodings[field.Type]
+						if jsonToType == "" && knownEncodings[field.Type] != "" {
+							jsonToType = knownEncodings[field.Type]
+						}
+						if docTag == "" && knownJSONToDocTags[jsonTo] != "" {
+							docTag = knownJSONToDocTags[jsonTo]
 						}
 						if docTag == "" && knownDocTags[field.Type] != "" {
 							docTag = knownDocTags[field.Type]
 						}
-						if jsonToTag != "" {
+						if jsonToType != "" {
 							result.Fields[name] = &JSONType{
 								In:          in,
 								ReflectType: field.Type,
-								Type:        jsonToTag,
+								Type:        jsonToType,
 								Comment:     docTag,
 							}
 						} else {
-							result.Fields[name] = newJSONTypeLoopProtector(append(seen, t), in, field.Type, filterOnScopes, scopeContexts, relevantScopes...)
+							result.Fields[name] = newJSONTypeLoopProtector(append(seen, t), in, field.Type, apiVersion, filterOnScopes, scopeContexts, relevantScopes...)
 							result.Fields[name].Comment = docTag
 						}
 						result.Fields[name].Scopes = updateScopes
@@ -318,7 +350,7 @@ func newJSONTypeLoopProtector(seen []reflect.Type, in bool, t reflect.Type, filt
 		}
 	case reflect.Slice:
 		result.Type = "Array"
-		result.Elem = newJSONTypeLoopProtector(append(seen, t), in, t.Elem(), filterOnScopes, scopeContexts, relevantScopes...)
+		result.Elem = newJSONTypeLoopProtector(append(seen, t), in, t.Elem(), apiVersion, filterOnScopes, scopeContexts, relevantScopes...)
 	default:
 		result.Type = t.Name()
 	}
@@ -333,7 +365,38 @@ type DefaultDocumentedRoute struct {
 	MaxAPIVersion int
 	In            *JSONType
 	Out           *JSONType
-	Comment       string
+	InSchema      *schema.Schema
+	OutSchema     *schema.Schema
+	// Versions are the shapes of In and Out in the API versions where they differ, if they have versioned fields.
+	Versions []*VersionedShape
+	Comment  string
+}
+
+/*
+VersionedShape documents the request and response bodies of a route in a range of API versions.
+*/
+type VersionedShape struct {
+	MinAPIVersion int
+	// MaxAPIVersion is 0 if the range has no end.
+	MaxAPIVersion int
+	In            *JSONType
+	Out           *JSONType
+	InSchema      *schema.Schema
+	OutSchema     *schema.Schema
+}
+
+/*
+InSchemaFor returns the schema of the request body in apiVersion, or the unversioned schema if apiVersion is 0.
+*/
+func (self *DefaultDocumentedRoute) InSchemaFor(apiVersion int) *schema.Schema {
+	if apiVersion != 0 {
+		for _, shape := range self.Versions {
+			if apiVersion >= shape.MinAPIVersion && (shape.MaxAPIVersion == 0 || apiVersion <= shape.MaxAPIVersion) {
+				return shape.InSchema
+			}
+		}
+	}
+	return self.InSchema
 }
 
 func (self *DefaultDocumentedRoute) GetScopes() []string {
@@ -464,14 +527,94 @@ func Document(fIn interface{}, path string, methods string, minAPIVersion, maxAP
 	// if the handler takes two arguments (that is, one decoded JSON body), add an input param type to document with.
 	// also send in the scopes, because the input type fields must be filtered so that those without scopes are ignored
 	if fType.NumIn() == 2 {
-		docRoute.In = newJSONType(true, fType.In(1), true, methodNames, scopes...)
+		docRoute.In = newJSONType(true, fType.In(1), 0, true, methodNames, scopes...)
+		docRoute.InSchema = schema.Generator{Contexts: methodNames, Scopes: scopes}.Generate(fType.In(1))
 	}
 	// if the handler provides three return values (that is, one decoded JSON body), add an output param type to document with.
 	if fType.NumOut() == 3 {
-		docRoute.Out = newJSONType(false, fType.Out(1), false, methodNames)
+		docRoute.Out = newJSONType(false, fType.Out(1), 0, false, methodNames)
+		docRoute.OutSchema = schema.Generator{}.Generate(fType.Out(1))
 	}
+	// if the bodies have versioned fields, document their shapes in each range of API versions where they stay the same
+	docRoute.Versions = documentVersions(fType, methodNames, minAPIVersion, maxAPIVersion, scopes)
 
 	fOut = CreateResponseFunc(fType, fVal)
+	// validate the request body against the input schema before it gets decoded
+	if docRoute.InSchema != nil {
+		respond := fOut
+		fOut = func(c JSONContextLogger) (response Resp, err error) {
+			if err = ValidateJSON(c, docRoute.InSchemaFor(c.APIVersion())); err != nil {
+				return
+			}
+			return respond(c)
+		}
+	}
+	return
+}
+
+/*
+documentVersions returns the shapes of the request and response bodies of a handler of fType in each range of API versions,
+between minAPIVersion and maxAPIVersion, where their fields stay the same. It returns nothing if the bodies have no versioned
+fields.
+*/
+func documentVersions(fType reflect.Type, methodNames []string, minAPIVersion, maxAPIVersion int, scopes []string) (result []*VersionedShape) {
+	changes := []int{}
+	if fType.NumIn() == 2 {
+		changes = append(changes, json.APIVersions(fType.In(1))...)
+	}
+	if fType.NumOut() == 3 {
+		changes = append(changes, json.APIVersions(fType.Out(1))...)
+	}
+	if len(changes) == 0 {
+		return
+	}
+	sort.Ints(changes)
+	// API version 0 means no version at all, so the first range starts at 1 unless the route requires more
+	starts := []int{minAPIVersion}
+	if starts[0] < 1 {
+		starts[0] = 1
+	}
+	for _, change := range changes {
+		if change > starts[len(starts)-1] && (maxAPIVersion == 0 || change <= maxAPIVersion) {
+			starts = append(starts, change)
+		}
+	}
+	for index, start := range starts {
+		shape := &VersionedShape{
+			MinAPIVersion: start,
+			MaxAPIVersion: maxAPIVersion,
+		}
+		if index+1 < len(starts) {
+			shape.MaxAPIVersion = starts[index+1] - 1
+		}
+		if fType.NumIn() == 2 {
+			shape.In = newJSONType(true, fType.In(1), start, true, methodNames, scopes...)
+			shape.InSchema = schema.Generator{Contexts: methodNames, Scopes: scopes, APIVersion: start}.Generate(fType.In(1))
+		}
+		if fType.NumOut() == 3 {
+			shape.Out = newJSONType(false, fType.Out(1), start, false, methodNames)
+			shape.OutSchema = schema.Generator{APIVersion: start}.Generate(fType.Out(1))
+		}
+		result = append(result, shape)
+	}
+	return
+}
+
+/*
+ValidateJSON will check the request body against s, and return a 400 listing the problems if it doesn't match.
+
+The body is left for DecodeJSON to decode.
+*/
+func ValidateJSON(c JSONContext, s *schema.Schema) (err error) {
+	body, err := ioutil.ReadAll(c.Req().Body)
+	if err != nil {
+		return
+	}
+	c.Req().Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err = s.Validate(body); err != nil {
+		mess := fmt.Sprintf("Unable to parse %#v as JSON: %v", string(body), err)
+		err = NewError(400, mess, mess, err)
+	}
 	return
 }
 